@@ -0,0 +1,187 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/retry"
+)
+
+// dayLayout is the format of menu and schedule dates returned by the API
+const dayLayout = "2006-01-02"
+
+// Exporter adds the meal menus and schedules of the children's centers and classes to calendars
+type Exporter struct {
+	client *kidsnote.Client
+	retry  retry.Policy
+}
+
+// NewExporter creates an Exporter retrying failed API calls according to the given policy
+func NewExporter(client *kidsnote.Client, retryPolicy retry.Policy) *Exporter {
+	return &Exporter{client: client, retry: retryPolicy}
+}
+
+// Months returns the first days of the months from back months before the current one to ahead months after it
+func Months(now time.Time, back, ahead int) []time.Time {
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var months []time.Time
+	for i := -back; i <= ahead; i++ {
+		months = append(months, current.AddDate(0, i, 0))
+	}
+	return months
+}
+
+// AddTo adds the menus and schedule events of the given months to cal. Centers and classes shared by
+// siblings are fetched once.
+func (e *Exporter) AddTo(ctx context.Context, cal *Calendar, children []*models.Child, months []time.Time) error {
+	centers := make(map[int]bool)
+	classes := make(map[int]bool)
+
+	for _, child := range children {
+		if child.CenterID != 0 && !centers[child.CenterID] {
+			centers[child.CenterID] = true
+			if err := e.addCenter(ctx, cal, child, months); err != nil {
+				return err
+			}
+		}
+
+		if child.ClassID != 0 && !classes[child.ClassID] {
+			classes[child.ClassID] = true
+			if err := e.addClass(ctx, cal, child, months); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *Exporter) addCenter(ctx context.Context, cal *Calendar, child *models.Child, months []time.Time) error {
+	for _, month := range months {
+		var menus []*models.Menu
+		err := e.retry.Do(ctx, func() error {
+			var err error
+			menus, err = e.client.Menus(ctx, child.CenterID, month)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get %s menus of center ID %d: %w", month.Format("2006-01"), child.CenterID, err)
+		}
+
+		for _, menu := range menus {
+			if event, ok := menuEvent(child.CenterID, menu); ok {
+				cal.Add(event)
+			}
+		}
+
+		var events []*models.ScheduleEvent
+		err = e.retry.Do(ctx, func() error {
+			var err error
+			events, err = e.client.CenterSchedule(ctx, child.CenterID, month)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get %s schedule of center ID %d: %w", month.Format("2006-01"), child.CenterID, err)
+		}
+
+		for _, event := range events {
+			if scheduled, ok := scheduleEvent(fmt.Sprintf("center-%d", child.CenterID), child.CenterName, event); ok {
+				cal.Add(scheduled)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *Exporter) addClass(ctx context.Context, cal *Calendar, child *models.Child, months []time.Time) error {
+	for _, month := range months {
+		var events []*models.ScheduleEvent
+		err := e.retry.Do(ctx, func() error {
+			var err error
+			events, err = e.client.ClassSchedule(ctx, child.ClassID, month)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get %s schedule of class ID %d: %w", month.Format("2006-01"), child.ClassID, err)
+		}
+
+		for _, event := range events {
+			if scheduled, ok := scheduleEvent(fmt.Sprintf("class-%d", child.ClassID), child.ClassName, event); ok {
+				cal.Add(scheduled)
+			}
+		}
+	}
+
+	return nil
+}
+
+// menuEvent turns a day's menu into an event summarized by the lunch, skipping menus without a valid date
+func menuEvent(centerID int, menu *models.Menu) (Event, bool) {
+	day, err := time.Parse(dayLayout, menu.Date)
+	if err != nil {
+		return Event{}, false
+	}
+
+	var meals []string
+	for _, meal := range []struct{ name, menu string }{
+		{"Morning snack", menu.MorningSnack},
+		{"Lunch", menu.Lunch},
+		{"Afternoon snack", menu.AfternoonSnack},
+		{"Dinner", menu.Dinner},
+	} {
+		if meal.menu != "" {
+			meals = append(meals, meal.name+": "+meal.menu)
+		}
+	}
+	if len(meals) == 0 {
+		return Event{}, false
+	}
+
+	summary := "Menu"
+	if menu.Lunch != "" {
+		summary += ": " + strings.Join(strings.Fields(menu.Lunch), " ")
+	}
+
+	return Event{
+		UID:         fmt.Sprintf("menu-%d-%s@kidsnoter", centerID, menu.Date),
+		Start:       day,
+		End:         day,
+		Summary:     summary,
+		Description: strings.Join(meals, "\n"),
+		Category:    "Menu",
+	}, true
+}
+
+// scheduleEvent turns a schedule entry of the given board (center or class) into an event, skipping
+// entries without a valid start date
+func scheduleEvent(board, boardName string, event *models.ScheduleEvent) (Event, bool) {
+	start, err := time.Parse(dayLayout, event.StartDate)
+	if err != nil {
+		return Event{}, false
+	}
+
+	end, err := time.Parse(dayLayout, event.EndDate)
+	if err != nil || end.Before(start) {
+		end = start
+	}
+
+	description := event.Content
+	if boardName != "" {
+		description = strings.TrimSpace(boardName + "\n\n" + description)
+	}
+
+	return Event{
+		UID:         fmt.Sprintf("schedule-%s-%d@kidsnoter", board, event.ID),
+		Start:       start,
+		End:         end,
+		Summary:     event.Title,
+		Description: description,
+		Category:    "Schedule",
+	}, true
+}
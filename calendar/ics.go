@@ -0,0 +1,168 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/karolistamutis/kidsnoter/util"
+)
+
+const (
+	dateLayout  = "20060102"
+	stampLayout = "20060102T150405Z"
+	// maxLineLength is the longest content line allowed by RFC 5545 in octets, longer ones are folded
+	maxLineLength = 75
+)
+
+// Event is an all-day event lasting from Start to End, both days inclusive
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Category    string
+}
+
+// Calendar is an iCalendar (RFC 5545) calendar of all-day events
+type Calendar struct {
+	Name   string
+	events map[string]Event
+}
+
+// New creates an empty calendar with the given display name
+func New(name string) *Calendar {
+	return &Calendar{Name: name, events: make(map[string]Event)}
+}
+
+// Add adds the event, replacing an earlier one with the same UID, e.g. the menu of a center shared by siblings
+func (c *Calendar) Add(event Event) {
+	c.events[event.UID] = event
+}
+
+// Len returns the number of events
+func (c *Calendar) Len() int {
+	return len(c.events)
+}
+
+// WriteTo writes the calendar in iCalendar format, with the events ordered by date
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	events := make([]Event, 0, len(c.events))
+	for _, event := range c.events {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].Start.Equal(events[j].Start) {
+			return events[i].Start.Before(events[j].Start)
+		}
+		return events[i].UID < events[j].UID
+	})
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	stamp := time.Now().UTC().Format(stampLayout)
+
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//kidsnoter//kidsnoter//EN")
+	cw.line("CALSCALE:GREGORIAN")
+	if c.Name != "" {
+		cw.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	for _, event := range events {
+		cw.line("BEGIN:VEVENT")
+		cw.line("UID:" + escape(event.UID))
+		cw.line("DTSTAMP:" + stamp)
+		cw.line("DTSTART;VALUE=DATE:" + event.Start.Format(dateLayout))
+		// The end of all-day events is exclusive
+		cw.line("DTEND;VALUE=DATE:" + event.End.AddDate(0, 0, 1).Format(dateLayout))
+		cw.line("SUMMARY:" + escape(event.Summary))
+		if event.Description != "" {
+			cw.line("DESCRIPTION:" + escape(event.Description))
+		}
+		if event.Category != "" {
+			cw.line("CATEGORIES:" + escape(event.Category))
+		}
+		cw.line("TRANSP:TRANSPARENT")
+		cw.line("END:VEVENT")
+	}
+	cw.line("END:VCALENDAR")
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// WriteFile writes the calendar to path. The file is replaced at once, so calendar apps subscribed to it
+// never read a partly written calendar.
+func (c *Calendar) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create calendar directory: %w", err)
+	}
+
+	f, err := util.CreateAtomic(path)
+	if err != nil {
+		return fmt.Errorf("failed to create calendar file: %w", err)
+	}
+	defer f.Abort()
+
+	if _, err := c.WriteTo(f); err != nil {
+		return fmt.Errorf("failed to write calendar file: %w", err)
+	}
+
+	if err := f.Commit(); err != nil {
+		return fmt.Errorf("failed to replace calendar file: %w", err)
+	}
+
+	return nil
+}
+
+// escape escapes a TEXT value
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// countingWriter writes content lines, folding long ones, and keeps the first error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) line(line string) {
+	for first := true; cw.err == nil; first = false {
+		limit := maxLineLength
+		prefix := ""
+		if !first {
+			// Continuation lines start with a space, which counts towards the limit
+			limit--
+			prefix = " "
+		}
+
+		if len(line) <= limit {
+			cw.write(prefix + line + "\r\n")
+			return
+		}
+
+		// Fold between characters, never inside a multi-byte one
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		cw.write(prefix + line[:cut] + "\r\n")
+		line = line[cut:]
+	}
+}
+
+func (cw *countingWriter) write(s string) {
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
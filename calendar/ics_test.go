@@ -0,0 +1,121 @@
+package calendar
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Lunch", "Lunch"},
+		{"Rice, soup; fruit", `Rice\, soup\; fruit`},
+		{`C:\menu`, `C:\\menu`},
+		{"Breakfast\nLunch\r\nSnack", `Breakfast\nLunch\nSnack`},
+	}
+
+	for _, tt := range tests {
+		if got := escape(tt.value); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Lunch"},
+		{"exactly the limit", "SUMMARY:" + strings.Repeat("a", maxLineLength-len("SUMMARY:"))},
+		{"long", "DESCRIPTION:" + strings.Repeat("rice and soup ", 20)},
+		{"multi-byte", "DESCRIPTION:" + strings.Repeat("김치볶음밥 ", 30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cw := &countingWriter{w: bufio.NewWriter(&buf)}
+			cw.line(tt.line)
+			if err := cw.w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			out := buf.String()
+			if cw.n != int64(len(out)) {
+				t.Errorf("counted %d bytes, wrote %d", cw.n, len(out))
+			}
+
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if len(tt.line) <= maxLineLength && len(lines) != 1 {
+				t.Errorf("folded a line of %d octets into %d", len(tt.line), len(lines))
+			}
+			for i, line := range lines {
+				if len(line) > maxLineLength {
+					t.Errorf("line %d is %d octets long, want at most %d", i, len(line), maxLineLength)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d was folded inside a character: %q", i, line)
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d doesn't start with a space: %q", i, line)
+				}
+			}
+
+			// Unfolding removes every line break followed by a space
+			if unfolded := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); unfolded != tt.line {
+				t.Errorf("unfolded to %q, want %q", unfolded, tt.line)
+			}
+		})
+	}
+}
+
+func TestCalendarWriteTo(t *testing.T) {
+	cal := New("Kid, class")
+	cal.Add(Event{UID: "menu-2", Start: date(2024, 5, 2), End: date(2024, 5, 2), Summary: "Lunch"})
+	cal.Add(Event{UID: "trip", Start: date(2024, 5, 1), End: date(2024, 5, 3), Summary: "Trip", Description: "Bring a hat\nand water", Category: "Schedule"})
+	// Replaces the first event with the same UID
+	cal.Add(Event{UID: "menu-2", Start: date(2024, 5, 2), End: date(2024, 5, 2), Summary: "Lunch, updated"})
+
+	if cal.Len() != 2 {
+		t.Errorf("calendar holds %d events, want 2", cal.Len())
+	}
+
+	var buf bytes.Buffer
+	n, err := cal.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo returned %d bytes, wrote %d", n, buf.Len())
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"X-WR-CALNAME:Kid\\, class\r\n",
+		"DTSTART;VALUE=DATE:20240501\r\nDTEND;VALUE=DATE:20240504\r\n",
+		"DESCRIPTION:Bring a hat\\nand water\r\n",
+		"CATEGORIES:Schedule\r\n",
+		"SUMMARY:Lunch\\, updated\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "UID:trip") > strings.Index(out, "UID:menu-2") {
+		t.Errorf("events aren't ordered by date:\n%s", out)
+	}
+	if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Errorf("calendar isn't wrapped in VCALENDAR:\n%s", out)
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/karolistamutis/kidsnoter/config"
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/karolistamutis/kidsnoter/listing"
+	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/ratelimit"
+	"github.com/karolistamutis/kidsnoter/session"
+	"github.com/spf13/cobra"
+)
+
+// account bundles the configuration and authenticated client of a single Kidsnote account
+type account struct {
+	config  *config.Account
+	store   *session.Store
+	session *session.Session
+	client  *kidsnote.Client
+	lister  listing.Lister
+}
+
+var (
+	accounts         []*account
+	selectedAccounts []string
+
+	// apiLimiter and mediaLimiter are shared by all accounts, which talk to the same servers
+	apiLimiter   *ratelimit.Limiter
+	mediaLimiter *ratelimit.Limiter
+)
+
+func initRateLimiters() {
+	apiRate, apiBurst := config.GetAPIRateLimit()
+	apiLimiter = ratelimit.New("api", apiRate, apiBurst)
+
+	mediaRate, mediaBurst := config.GetMediaRateLimit()
+	mediaLimiter = ratelimit.New("media", mediaRate, mediaBurst)
+}
+
+func addAccountFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(&selectedAccounts, "account", nil, "Limit to the given account names, defaults to all configured accounts")
+}
+
+// configuredAccounts returns the accounts from configuration, limited to the ones chosen with --account
+func configuredAccounts() ([]*config.Account, error) {
+	all, err := config.GetAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(selectedAccounts) == 0 {
+		return all, nil
+	}
+
+	var selected []*config.Account
+	for _, name := range selectedAccounts {
+		found := false
+		for _, acc := range all {
+			if acc.Name == name {
+				selected = append(selected, acc)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("account %q is not configured", name)
+		}
+	}
+
+	return selected, nil
+}
+
+// selectChildren lists the account's children, applying the account's child filters and the given child ID or name.
+// A child ID or name that belongs to another account results in an empty list.
+func (a *account) selectChildren(ctx context.Context, childID int, childName string) ([]*models.Child, error) {
+	children, err := a.lister.ListChildren(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing children for account %s: %w", a.config.Name, err)
+	}
+
+	var selected []*models.Child
+	for _, child := range children {
+		if !a.includesChild(child) {
+			continue
+		}
+		if childID != 0 && child.ID != childID {
+			continue
+		}
+		if childName != "" && child.Name != childName {
+			continue
+		}
+		selected = append(selected, child)
+	}
+
+	return selected, nil
+}
+
+// includesChild reports whether the child passes the account's child_ids and child_names filters
+func (a *account) includesChild(child *models.Child) bool {
+	if len(a.config.ChildIDs) == 0 && len(a.config.ChildNames) == 0 {
+		return true
+	}
+	for _, id := range a.config.ChildIDs {
+		if id == child.ID {
+			return true
+		}
+	}
+	for _, name := range a.config.ChildNames {
+		if name == child.Name {
+			return true
+		}
+	}
+	return false
+}
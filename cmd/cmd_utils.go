@@ -1,9 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/karolistamutis/kidsnoter/config"
+	"github.com/karolistamutis/kidsnoter/downloading"
+	"github.com/karolistamutis/kidsnoter/listing"
+	"github.com/karolistamutis/kidsnoter/logger"
 	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/output"
+	"github.com/karolistamutis/kidsnoter/util"
 	"github.com/spf13/cobra"
 )
 
@@ -25,21 +36,179 @@ func getChildFlags(cmd *cobra.Command) (int, string, error) {
 	return childID, childName, nil
 }
 
-func filterChildren(children []*models.Child, childID int, childName string) ([]*models.Child, error) {
-	if childID == 0 && childName == "" {
-		return children, nil
+// accountChildren holds the children selected for processing within one account
+type accountChildren struct {
+	account  *account
+	children []*models.Child
+}
+
+// selectAccountChildren returns the children to process for every logged on account
+func selectAccountChildren(ctx context.Context, childID int, childName string) ([]accountChildren, error) {
+	var selected []accountChildren
+	found := 0
+	for _, acc := range accounts {
+		children, err := acc.selectChildren(ctx, childID, childName)
+		if err != nil {
+			return nil, err
+		}
+		found += len(children)
+		selected = append(selected, accountChildren{account: acc, children: children})
+	}
+
+	if found == 0 && (childID != 0 || childName != "") {
+		return nil, fmt.Errorf("couldn't find given child information")
+	}
+
+	return selected, nil
+}
+
+// getAlbumFilter reads the flags added by addAlbumFilterFlags, or just the ones added by addDateFilterFlags
+func getAlbumFilter(cmd *cobra.Command) (*listing.ListOptions, error) {
+	now := time.Now()
+
+	sinceFlag, err := cmd.Flags().GetString("since")
+	if err != nil {
+		return nil, fmt.Errorf("error getting since flag: %w", err)
+	}
+	since, err := util.ParseTimeBound(sinceFlag, now, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --since: %w", err)
+	}
+
+	untilFlag, err := cmd.Flags().GetString("until")
+	if err != nil {
+		return nil, fmt.Errorf("error getting until flag: %w", err)
+	}
+	until, err := util.ParseTimeBound(untilFlag, now, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --until: %w", err)
+	}
+
+	if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+		return nil, fmt.Errorf("--since must be before --until")
+	}
+
+	filter := &listing.ListOptions{
+		Since: since,
+		Until: until,
+	}
+	if cmd.Flags().Lookup("album-id") == nil {
+		return filter, nil
+	}
+
+	albumIDs, err := cmd.Flags().GetIntSlice("album-id")
+	if err != nil {
+		return nil, fmt.Errorf("error getting album-id flag: %w", err)
+	}
+
+	titleMatchFlag, err := cmd.Flags().GetString("title-match")
+	if err != nil {
+		return nil, fmt.Errorf("error getting title-match flag: %w", err)
+	}
+	filter.AlbumIDs = albumIDs
+	if titleMatchFlag != "" {
+		filter.TitleMatch, err = regexp.Compile(titleMatchFlag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --title-match: %w", err)
+		}
+	}
+
+	return filter, nil
+}
+
+func addAlbumFilterFlags(cmd *cobra.Command) {
+	addDateFilterFlags(cmd, "albums")
+	cmd.Flags().IntSlice("album-id", nil, "Only the albums with these IDs, check with ./kidsnoter list-albums")
+	cmd.Flags().String("title-match", "", "Only albums whose title matches this regular expression")
+}
+
+// addDateFilterFlags adds --since and --until, kind names what they select in the help text, e.g. albums
+func addDateFilterFlags(cmd *cobra.Command, kind string) {
+	cmd.Flags().String("since", "", "Only "+kind+" created on or after this date (2024-05-01) or this long ago (30d, 2w, 12h)")
+	cmd.Flags().String("until", "", "Only "+kind+" created up to this date (2024-05-31, inclusive) or this long ago (7d)")
+}
+
+// getDownloadOptions reads the flags added by addDownloadFlags. The file pool of the options is shared by the downloaders
+// of all accounts, so file_concurrency bounds the whole run; the caller closes it once done.
+func getDownloadOptions(cmd *cobra.Command) (downloading.Options, error) {
+	overwrite, err := cmd.Flags().GetBool("overwrite")
+	if err != nil {
+		return downloading.Options{}, fmt.Errorf("error getting overwrite flag: %w", err)
 	}
 
-	for _, child := range children {
-		if child.ID == childID || child.Name == childName {
-			return []*models.Child{child}, nil
+	full, err := cmd.Flags().GetBool("full")
+	if err != nil {
+		return downloading.Options{}, fmt.Errorf("error getting full flag: %w", err)
+	}
+
+	albumConcurrency, fileConcurrency := config.GetDownloadConcurrency()
+	opts := downloading.Options{
+		Overwrite:        overwrite,
+		Full:             full,
+		Retry:            config.GetRetryPolicies(),
+		RecheckWindow:    config.GetRecheckWindow(),
+		AlbumConcurrency: albumConcurrency,
+	}
+
+	if cmd.Flags().Lookup("since") != nil {
+		filter, err := getAlbumFilter(cmd)
+		if err != nil {
+			return downloading.Options{}, err
 		}
+		opts.Filter = *filter
 	}
 
-	return nil, fmt.Errorf("couldn't find given child information")
+	opts.Files = downloading.NewFilePool(fileConcurrency)
+	return opts, nil
+}
+
+func addDownloadFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("overwrite", false, "Overwrite existing files in the output directory, implies --full")
+	cmd.Flags().Bool("full", false, "List everything instead of stopping at what an earlier run synced")
+}
+
+// newPrinter creates a printer from the flags added by addOutputFlags
+func newPrinter(cmd *cobra.Command) (*output.Printer, error) {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return nil, fmt.Errorf("error getting output flag: %w", err)
+	}
+
+	tmpl, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return nil, fmt.Errorf("error getting format flag: %w", err)
+	}
+
+	return output.NewPrinter(os.Stdout, format, tmpl)
+}
+
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "table", "Output format, one of "+strings.Join(output.Formats, ", "))
+	cmd.Flags().String("format", "", "Go template for each item, e.g. '{{.ID}} {{.Name}}', overrides --output")
 }
 
 func addChildFlags(cmd *cobra.Command) {
 	cmd.Flags().Int("child-id", 0, "Child's ID on kidsnote.com, check with ./kidsnoter list-children")
 	cmd.Flags().String("child-name", "", "Child's name on kidsnote.com, must match output of ./kidsnoter list-children")
 }
+
+// stalePartAge is how long an unfinished file has to go unmodified before it counts as left by an interrupted run,
+// downloads in progress write to theirs far more often
+const stalePartAge = time.Hour
+
+// removeStaleParts deletes the unfinished files left in the album directory by an interrupted run
+func removeStaleParts(albumDir string) {
+	dir, err := util.ExpandTilde(albumDir)
+	if err != nil {
+		logger.Log.Warnf("Failed to expand album directory path %s: %v", albumDir, err)
+		return
+	}
+
+	removed, err := util.RemoveStaleParts(dir, stalePartAge)
+	if err != nil {
+		logger.Log.Warnf("Failed to remove unfinished files in %s: %v", dir, err)
+	}
+	if removed > 0 {
+		logger.Log.Infof("Removed %d unfinished file(s) left in %s by an interrupted run", removed, dir)
+	}
+}
@@ -2,10 +2,9 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/karolistamutis/kidsnoter/config"
 	"github.com/karolistamutis/kidsnoter/downloading"
-	"github.com/karolistamutis/kidsnoter/listing"
 	"github.com/karolistamutis/kidsnoter/logger"
 	"github.com/karolistamutis/kidsnoter/models"
 	"github.com/spf13/cobra"
@@ -16,14 +15,19 @@ var downloadAlbumsCmd = &cobra.Command{
 	Short: "Download the photo albums of a child",
 	Long: `This command allows you to download the albums for a given child ID or name.
 
-Specify either --child-id ID or --child-name NAME to proceed. If neither is specified, it will download albums for all children in the account.`,
+Specify either --child-id ID or --child-name NAME to proceed. If neither is specified, it will download albums for all children in the selected accounts.
+
+Listing stops at the newest album of the last download that finished without errors, use --full to check all albums.
+Use --since and --until to download only the albums created in a date range, e.g. --since 30d, and --album-id or
+--title-match to pick albums by ID or title.`,
 	RunE: runDownloadAlbums,
 }
 
 func init() {
 	addChildFlags(downloadAlbumsCmd)
-	downloadAlbumsCmd.Flags().Bool("overwrite", false, "Overwrite existing files in the output directory")
-	RootCmd.AddCommand(downloadAlbumsCmd)
+	addAlbumFilterFlags(downloadAlbumsCmd)
+	addDownloadFlags(downloadAlbumsCmd)
+	RootCmd.AddCommand(requireLogin(downloadAlbumsCmd))
 }
 
 func runDownloadAlbums(cmd *cobra.Command, args []string) error {
@@ -34,39 +38,43 @@ func runDownloadAlbums(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	albumDir := config.GetAlbumDir()
-	if albumDir == "" {
-		return fmt.Errorf("missing or empty invalid album_dir setting")
-	}
-
-	overwrite, err := cmd.Flags().GetBool("overwrite")
+	opts, err := getDownloadOptions(cmd)
 	if err != nil {
-		return fmt.Errorf("error getting overwrite flag: %w", err)
+		return err
 	}
-	logger.Log.Debugf("overwrite flag is: %v", overwrite)
+	defer opts.Files.Close()
+	logger.Log.Debugf("overwrite flag is: %v, full flag is: %v", opts.Overwrite, opts.Full)
 
-	lister := listing.NewLister(client)
-	downloader, err := downloading.NewDownloader(lister, client, overwrite)
+	selected, err := selectAccountChildren(ctx, childID, childName)
 	if err != nil {
-		return fmt.Errorf("error creating downloader: %w", err)
+		return err
 	}
 
-	children, err := lister.ListChildren(ctx)
-	if err != nil {
-		return fmt.Errorf("error listing children: %w", err)
-	}
+	var errs []error
+	for _, s := range selected {
+		albumDir := s.account.config.AlbumDir
+		if albumDir == "" {
+			return fmt.Errorf("missing or empty invalid album_dir setting for account %s", s.account.config.Name)
+		}
+		removeStaleParts(albumDir)
 
-	childrenToProcess, err := filterChildren(children, childID, childName)
-	if err != nil {
-		return err
-	}
+		downloader, err := downloading.NewDownloader(s.account.lister, s.account.client, opts)
+		if err != nil {
+			return fmt.Errorf("error creating downloader: %w", err)
+		}
 
-	for _, child := range childrenToProcess {
-		if err := downloadAlbumsForChild(ctx, downloader, child, albumDir); err != nil {
-			return err
+		// A failed child doesn't keep the albums of the others from being downloaded
+		for _, child := range s.children {
+			if err := downloadAlbumsForChild(ctx, downloader, child, albumDir); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
 
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
 	fmt.Println("Albums downloaded successfully")
 	return nil
 }
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/karolistamutis/kidsnoter/downloading"
+	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/spf13/cobra"
+)
+
+var downloadNoticesCmd = &cobra.Command{
+	Use:   "download-notices",
+	Short: "Download the notices of a child's center and class",
+	Long: `This command allows you to download the notices posted to the center and the class of a given child ID or name.
+
+Specify either --child-id ID or --child-name NAME to proceed. If neither is specified, it will download notices for all children in the selected accounts.
+Siblings in the same center or class share its notices, which are downloaded once.
+
+Every notice is saved as a markdown file along with its photos, video and attached files, under notices/CENTER/CLASS
+in the album directory. All notices are checked on every run, so edited ones are updated, as if --full was given.
+Use --since and --until to download only the notices posted in a date range, e.g. --since 30d.`,
+	RunE: runDownloadNotices,
+}
+
+func init() {
+	addChildFlags(downloadNoticesCmd)
+	addDateFilterFlags(downloadNoticesCmd, "notices")
+	addDownloadFlags(downloadNoticesCmd)
+	RootCmd.AddCommand(requireLogin(downloadNoticesCmd))
+}
+
+func runDownloadNotices(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	childID, childName, err := getChildFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	opts, err := getDownloadOptions(cmd)
+	if err != nil {
+		return err
+	}
+	defer opts.Files.Close()
+	logger.Log.Debugf("overwrite flag is: %v, full flag is: %v", opts.Overwrite, opts.Full)
+
+	selected, err := selectAccountChildren(ctx, childID, childName)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, s := range selected {
+		albumDir := s.account.config.AlbumDir
+		if albumDir == "" {
+			return fmt.Errorf("missing or empty invalid album_dir setting for account %s", s.account.config.Name)
+		}
+		removeStaleParts(albumDir)
+
+		downloader, err := downloading.NewDownloader(s.account.lister, s.account.client, opts)
+		if err != nil {
+			return fmt.Errorf("error creating downloader: %w", err)
+		}
+
+		// A failed account doesn't keep the notices of the others from being downloaded
+		if err := downloader.DownloadNotices(ctx, s.children, albumDir); err != nil {
+			errs = append(errs, fmt.Errorf("error downloading notices for account %s: %w", s.account.config.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	fmt.Println("Notices downloaded successfully")
+	return nil
+}
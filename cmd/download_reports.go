@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/karolistamutis/kidsnoter/downloading"
+	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/spf13/cobra"
+)
+
+var downloadReportsCmd = &cobra.Command{
+	Use:   "download-reports",
+	Short: "Download the daily reports of a child",
+	Long: `This command allows you to download the daily reports (알림장) for a given child ID or name.
+
+Specify either --child-id ID or --child-name NAME to proceed. If neither is specified, it will download reports for all children in the selected accounts.
+
+Every report is saved as a markdown file with the teacher's notes, the daily status and the replies, along with
+the attached photos and video, in the reports folder of the child next to the albums.
+
+Listing stops at the newest report of the last download that finished without errors, use --full to check all reports.
+Use --since and --until to download only the reports created in a date range, e.g. --since 30d.`,
+	RunE: runDownloadReports,
+}
+
+func init() {
+	addChildFlags(downloadReportsCmd)
+	addDateFilterFlags(downloadReportsCmd, "reports")
+	addDownloadFlags(downloadReportsCmd)
+	RootCmd.AddCommand(requireLogin(downloadReportsCmd))
+}
+
+func runDownloadReports(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	childID, childName, err := getChildFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	opts, err := getDownloadOptions(cmd)
+	if err != nil {
+		return err
+	}
+	defer opts.Files.Close()
+	logger.Log.Debugf("overwrite flag is: %v, full flag is: %v", opts.Overwrite, opts.Full)
+
+	selected, err := selectAccountChildren(ctx, childID, childName)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, s := range selected {
+		albumDir := s.account.config.AlbumDir
+		if albumDir == "" {
+			return fmt.Errorf("missing or empty invalid album_dir setting for account %s", s.account.config.Name)
+		}
+		removeStaleParts(albumDir)
+
+		downloader, err := downloading.NewDownloader(s.account.lister, s.account.client, opts)
+		if err != nil {
+			return fmt.Errorf("error creating downloader: %w", err)
+		}
+
+		// A failed child doesn't keep the reports of the others from being downloaded
+		for _, child := range s.children {
+			if err := downloadReportsForChild(ctx, downloader, child, albumDir); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	fmt.Println("Reports downloaded successfully")
+	return nil
+}
+
+func downloadReportsForChild(ctx context.Context, downloader *downloading.Downloader, child *models.Child, outputDir string) error {
+	err := downloader.DownloadReports(ctx, child.ID, outputDir)
+	if err != nil {
+		return fmt.Errorf("error downloading reports for %s: %w", child.Name, err)
+	}
+	return nil
+}
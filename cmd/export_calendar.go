@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/calendar"
+	"github.com/karolistamutis/kidsnoter/config"
+	"github.com/karolistamutis/kidsnoter/util"
+	"github.com/spf13/cobra"
+)
+
+var exportCalendarCmd = &cobra.Command{
+	Use:   "export-calendar",
+	Short: "Export meal menus and schedules to an iCalendar file",
+	Long: `This command exports the meal menus of a child's center and the schedules of the center and the class
+to an iCalendar (.ics) file, which calendar apps can import or subscribe to.
+
+Specify either --child-id ID or --child-name NAME to proceed. If neither is specified, it will export the calendars of all children
+in the selected accounts into one file.
+
+Without --file the calendar is written to standard output. serve can keep a calendar file up to date in the album directory,
+see the calendar settings.`,
+	RunE: runExportCalendar,
+}
+
+func init() {
+	addChildFlags(exportCalendarCmd)
+	exportCalendarCmd.Flags().String("file", "", "Write the calendar to this file instead of standard output")
+	exportCalendarCmd.Flags().Int("months-back", 0, "Number of past months to export (default: calendar.months_back setting)")
+	exportCalendarCmd.Flags().Int("months-ahead", 0, "Number of upcoming months to export (default: calendar.months_ahead setting)")
+	RootCmd.AddCommand(requireLogin(exportCalendarCmd))
+}
+
+func runExportCalendar(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	childID, childName, err := getChildFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	file, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return fmt.Errorf("error getting file flag: %w", err)
+	}
+
+	back, ahead := config.GetCalendarMonths()
+	if cmd.Flags().Changed("months-back") {
+		if back, err = cmd.Flags().GetInt("months-back"); err != nil {
+			return fmt.Errorf("error getting months-back flag: %w", err)
+		}
+	}
+	if cmd.Flags().Changed("months-ahead") {
+		if ahead, err = cmd.Flags().GetInt("months-ahead"); err != nil {
+			return fmt.Errorf("error getting months-ahead flag: %w", err)
+		}
+	}
+	if back < 0 || ahead < 0 {
+		return fmt.Errorf("--months-back and --months-ahead can't be negative")
+	}
+
+	selected, err := selectAccountChildren(ctx, childID, childName)
+	if err != nil {
+		return err
+	}
+
+	cal := calendar.New("Kidsnote")
+	months := calendar.Months(time.Now(), back, ahead)
+	for _, s := range selected {
+		exporter := calendar.NewExporter(s.account.client, config.GetRetryPolicies().Listing)
+		if err := exporter.AddTo(ctx, cal, s.children, months); err != nil {
+			return fmt.Errorf("error exporting calendar for account %s: %w", s.account.config.Name, err)
+		}
+	}
+
+	if file == "" {
+		_, err := cal.WriteTo(os.Stdout)
+		return err
+	}
+
+	if err := cal.WriteFile(file); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d events to %s\n", cal.Len(), file)
+	return nil
+}
+
+// exportCalendars writes the calendars of the synchronized accounts' children to the calendar files in their album
+// directories. Accounts sharing an album directory share the file, so their calendars are merged into it.
+// A file is left as it was if exporting any of its accounts fails.
+func exportCalendars(ctx context.Context, syncs []*accountSync) error {
+	back, ahead := config.GetCalendarMonths()
+	months := calendar.Months(time.Now(), back, ahead)
+
+	var files []string
+	cals := make(map[string]*calendar.Calendar)
+	failed := make(map[string]bool)
+	var errs []error
+	for _, s := range syncs {
+		file, err := calendarFile(s.account)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		cal, ok := cals[file]
+		if !ok {
+			cal = calendar.New("Kidsnote")
+			cals[file] = cal
+			files = append(files, file)
+		}
+
+		exporter := calendar.NewExporter(s.account.client, config.GetRetryPolicies().Listing)
+		if err := exporter.AddTo(ctx, cal, s.children, months); err != nil {
+			errs = append(errs, fmt.Errorf("error exporting calendar for account %s: %w", s.account.config.Name, err))
+			failed[file] = true
+		}
+	}
+
+	for _, file := range files {
+		if failed[file] {
+			continue
+		}
+		if err := cals[file].WriteFile(file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// calendarFile returns the path of the calendar file serve keeps up to date for the account
+func calendarFile(acc *account) (string, error) {
+	albumDir, err := util.ExpandTilde(acc.config.AlbumDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand album directory path: %w", err)
+	}
+
+	file := config.GetCalendarFile()
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(albumDir, file)
+	}
+	return filepath.Clean(file), nil
+}
@@ -2,10 +2,15 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strconv"
 
 	"github.com/karolistamutis/kidsnoter/listing"
 	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/output"
+	"github.com/karolistamutis/kidsnoter/util"
 	"github.com/spf13/cobra"
 )
 
@@ -14,13 +19,19 @@ var listAlbumsCmd = &cobra.Command{
 	Short: "List the photo albums of a child",
 	Long: `This command allows you to list the albums for a given child ID or name.
 
-Specify either --child-id ID or --child-name NAME to proceed. If neither is specified, it will list albums for all children in the account.`,
+Specify either --child-id ID or --child-name NAME to proceed. If neither is specified, it will list albums for all children in the selected accounts.
+Use --since and --until to list only the albums created in a date range, e.g. --since 30d, and --album-id or
+--title-match to pick albums by ID or title.
+
+Use --output to print json, jsonl, csv or yaml instead of a table, or --format with a Go template, e.g. --format '{{.ID}} {{.Title}}'.`,
 	RunE: runListAlbums,
 }
 
 func init() {
 	addChildFlags(listAlbumsCmd)
-	RootCmd.AddCommand(listAlbumsCmd)
+	addAlbumFilterFlags(listAlbumsCmd)
+	addOutputFlags(listAlbumsCmd)
+	RootCmd.AddCommand(requireLogin(listAlbumsCmd))
 }
 
 func runListAlbums(cmd *cobra.Command, args []string) error {
@@ -31,52 +42,116 @@ func runListAlbums(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	lister := listing.NewLister(client)
+	opts, err := getAlbumFilter(cmd)
+	if err != nil {
+		return err
+	}
 
-	children, err := lister.ListChildren(ctx)
+	printer, err := newPrinter(cmd)
 	if err != nil {
-		return fmt.Errorf("error listing children: %w", err)
+		return err
 	}
 
-	childrenToProcess, err := filterChildren(children, childID, childName)
+	selected, err := selectAccountChildren(ctx, childID, childName)
 	if err != nil {
 		return err
 	}
 
-	for _, child := range childrenToProcess {
-		if err := listAlbumsForChild(ctx, lister, child); err != nil {
-			return err
+	// A failed child doesn't keep the albums of the others from being listed
+	var errs []error
+	for _, s := range selected {
+		for _, child := range s.children {
+			if err := listAlbumsForChild(ctx, s.account, child, opts, printer); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
 
-	return nil
+	if err := printer.Flush(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
 }
 
-func listAlbumsForChild(ctx context.Context, lister listing.Lister, child *models.Child) error {
-	fmt.Printf("Listing albums for %s (ID: %d)\n", child.Name, child.ID)
+// albumRow is an album as printed by list-albums, along with a summary of its media and where it's saved
+type albumRow struct {
+	Account   string `json:"account"`
+	ChildID   int    `json:"child_id"`
+	ChildName string `json:"child_name"`
+	*models.Album
+	Enrollment *models.Enrollment `json:"child_enrollment,omitempty"`
+	ImageCount int                `json:"image_count"`
+	HasVideo   bool               `json:"has_video"`
+	TotalSize  int                `json:"total_size"`
+	Folder     string             `json:"folder"`
+	Path       string             `json:"path,omitempty"`
+}
 
-	albumChan := make(chan *models.Album)
-	errChan := make(chan error, 1)
+// className returns the child's class at the time the album was posted
+func (r *albumRow) className() string {
+	if r.Enrollment == nil {
+		return ""
+	}
+	return r.Enrollment.ClassName
+}
 
-	go func() {
-		defer close(albumChan)
-		defer close(errChan)
-		if err := lister.ListAlbums(ctx, child.ID, albumChan); err != nil {
-			errChan <- fmt.Errorf("error listing albums: %w", err)
-		}
-	}()
+func newAlbumRow(acc *account, child *models.Child, album *models.Album) *albumRow {
+	row := &albumRow{
+		Account:    acc.config.Name,
+		ChildID:    child.ID,
+		ChildName:  child.Name,
+		Album:      album,
+		Enrollment: album.Enrollment,
+		ImageCount: len(album.Images),
+		HasVideo:   album.Video != nil,
+		Folder:     album.GeneratedFolderName,
+	}
 
-	albumCount := 0
-	for album := range albumChan {
-		fmt.Printf("ID [%d], on %s, \"%s\"\n", album.ID, album.Date, album.Title)
-		albumCount++
+	for _, image := range album.Images {
+		row.TotalSize += image.FileSize
+	}
+	if album.Video != nil {
+		row.TotalSize += album.Video.FileSize
 	}
 
-	if err := <-errChan; err != nil {
-		return err
+	if albumDir, err := util.ExpandTilde(acc.config.AlbumDir); err == nil && albumDir != "" {
+		row.Path = filepath.Join(albumDir, album.GeneratedFolderName)
+	}
+
+	return row
+}
+
+func (r *albumRow) Columns() []output.Column {
+	return []output.Column{
+		{Name: "account", Value: r.Account, Wide: true},
+		{Name: "child_id", Value: strconv.Itoa(r.ChildID), Wide: true},
+		{Name: "child_name", Value: r.ChildName},
+		{Name: "id", Value: strconv.Itoa(r.ID)},
+		{Name: "created", Value: r.Date},
+		{Name: "title", Value: r.Title},
+		{Name: "content", Value: r.Content, Wide: true},
+		{Name: "class", Value: r.className(), Wide: true},
+		{Name: "images", Value: strconv.Itoa(r.ImageCount)},
+		{Name: "video", Value: strconv.FormatBool(r.HasVideo)},
+		{Name: "total_size", Value: strconv.Itoa(r.TotalSize)},
+		{Name: "folder", Value: r.Folder, Wide: true},
+		{Name: "path", Value: r.Path, Wide: true},
 	}
+}
+
+func listAlbumsForChild(ctx context.Context, acc *account, child *models.Child, opts *listing.ListOptions, printer *output.Printer) error {
+	albums := acc.lister.Albums(ctx, child.ID, opts)
+	// Stops listing when printing fails
+	defer albums.Close()
 
-	fmt.Printf("\nTotal of %d albums for %s\n\n", albumCount, child.Name)
+	for albums.Next() {
+		if err := printer.Print(newAlbumRow(acc, child, albums.Album())); err != nil {
+			return err
+		}
+	}
 
+	if err := albums.Err(); err != nil {
+		return fmt.Errorf("error listing albums for %s: %w", child.Name, err)
+	}
 	return nil
 }
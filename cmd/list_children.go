@@ -2,39 +2,73 @@ package cmd
 
 import (
 	"context"
-	"fmt"
+	"strconv"
 
-	"github.com/karolistamutis/kidsnoter/listing"
+	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/output"
 	"github.com/spf13/cobra"
 )
 
 var listChildrenCmd = &cobra.Command{
 	Use:   "list-children",
 	Short: "List the children under your account",
-	Long:  `This command allows you to list the children associated with your account (i.e. your kids).`,
-	RunE:  runListChildren,
+	Long: `This command allows you to list the children associated with your account (i.e. your kids).
+Children left out by an account's child_ids or child_names settings aren't listed.
+
+Use --output to print json, jsonl, csv or yaml instead of a table, or --format with a Go template, e.g. --format '{{.ID}} {{.Name}}'.`,
+	RunE: runListChildren,
 }
 
 func init() {
-	RootCmd.AddCommand(listChildrenCmd)
+	addOutputFlags(listChildrenCmd)
+	RootCmd.AddCommand(requireLogin(listChildrenCmd))
+}
+
+// childRow is a child as printed by list-children
+type childRow struct {
+	Account string `json:"account"`
+	*models.Child
+}
+
+func (r *childRow) Columns() []output.Column {
+	return []output.Column{
+		{Name: "account", Value: r.Account},
+		{Name: "id", Value: strconv.Itoa(r.ID)},
+		{Name: "name", Value: r.Name},
+		{Name: "gender", Value: r.Gender, Wide: true},
+		{Name: "date_of_birth", Value: r.DateOfBirth},
+		{Name: "center_id", Value: strconv.Itoa(r.CenterID)},
+		{Name: "class_id", Value: strconv.Itoa(r.ClassID)},
+		{Name: "center_name", Value: r.CenterName, Wide: true},
+		{Name: "class_name", Value: r.ClassName, Wide: true},
+	}
 }
 
 func runListChildren(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
-	return listChildren(ctx)
-}
 
-func listChildren(ctx context.Context) error {
-	lister := listing.NewLister(client)
-	children, err := lister.ListChildren(ctx)
+	printer, err := newPrinter(cmd)
 	if err != nil {
-		return fmt.Errorf("error listing children: %w", err)
+		return err
 	}
 
-	for _, child := range children {
-		fmt.Printf("%s [%d], date of birth: %s, center: %d, class: %d\n",
-			child.Name, child.ID, child.DateOfBirth, child.CenterID, child.ClassID)
+	return listChildren(ctx, printer)
+}
+
+func listChildren(ctx context.Context, printer *output.Printer) error {
+	for _, acc := range accounts {
+		// Like the other commands, only the children passing the account's child_ids and child_names filters
+		children, err := acc.selectChildren(ctx, 0, "")
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			if err := printer.Print(&childRow{Account: acc.config.Name, Child: child}); err != nil {
+				return err
+			}
+		}
 	}
 
-	return nil
+	return printer.Flush()
 }
@@ -1,123 +1,297 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/karolistamutis/kidsnoter/config"
+	"github.com/karolistamutis/kidsnoter/credentials"
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/karolistamutis/kidsnoter/listing"
 	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/karolistamutis/kidsnoter/session"
 	"github.com/spf13/cobra"
-	"github.com/valyala/fastjson"
-	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"slices"
+	"time"
 )
 
-var (
-	client     *http.Client
-	isLoggedOn bool
-)
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Log in to Kidsnote and save the session",
+	Long: `This command logs in with your username and password, even if a stored session is still valid, and saves the new session for the following runs.
+
+Use it to check that your credentials work.`,
+	RunE: runLogin,
+}
 
-type loginRequest struct {
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-	RememberMe bool   `json:"remember_me"`
+func init() {
+	RootCmd.AddCommand(loginCmd)
+}
+
+func runLogin(cmd *cobra.Command, args []string) error {
+	if err := loginAccounts(cmd.Context(), false); err != nil {
+		return err
+	}
+
+	for _, acc := range accounts {
+		fmt.Printf("Logged in to account %s as %s, session saved to %s\n",
+			acc.config.Name, acc.config.Username, acc.store.Path(acc.config.Username))
+	}
+	return nil
 }
 
 func loginPreRun(cmd *cobra.Command, args []string) error {
-	if isLoggedOn {
+	if !requiresLogin(cmd) {
+		logger.Log.Debugf("Command %s works offline, skipping login", cmd.Name())
+		return nil
+	}
+
+	if IsLoggedOn() {
 		logger.Log.Debug("Already logged in")
 		return nil
 	}
 
-	jar, err := cookiejar.New(nil)
+	return loginAccounts(context.Background(), true)
+}
+
+// loginAccounts logs in to every selected account, optionally reusing stored sessions
+func loginAccounts(ctx context.Context, reuseSession bool) error {
+	configs, err := configuredAccounts()
 	if err != nil {
-		return fmt.Errorf("failed to create cookie jar: %w", err)
+		return err
+	}
+
+	store, err := session.NewStore(config.GetSessionDir())
+	if err != nil {
+		return fmt.Errorf("failed to open session store: %w", err)
 	}
 
-	client = &http.Client{Jar: jar}
+	loggedOn := make([]*account, 0, len(configs))
+	for _, cfg := range configs {
+		acc := &account{config: cfg, store: store}
+		if err := acc.login(ctx, reuseSession); err != nil {
+			return fmt.Errorf("account %s: %w", cfg.Name, err)
+		}
+		loggedOn = append(loggedOn, acc)
+	}
+	accounts = loggedOn
+
+	return nil
+}
 
-	username := config.GetUsername()
-	password := config.GetPassword()
+// login authenticates the account, reusing the stored session when allowed and still valid
+func (a *account) login(ctx context.Context, reuseSession bool) error {
+	creds := credentials.NewChain(a.config.Username, a.credentialProviders()...)
 
-	if username == "" || password == "" {
-		return fmt.Errorf("username or password is not set in configuration")
+	// Without a configured username the only option is to ask for both username and password
+	if a.config.Username == "" {
+		resolved, err := creds.Resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("username is not set in configuration: %w", err)
+		}
+		a.config.Username = resolved.Username
+		if a.config.Name == "" {
+			a.config.Name = resolved.Username
+		}
 	}
+	username := a.config.Username
 
-	loginData := loginRequest{
-		Username:   username,
-		Password:   password,
-		RememberMe: true,
+	if err := a.newClient(creds); err != nil {
+		return err
 	}
 
-	jsonLoginData, err := json.Marshal(loginData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal login data: %w", err)
+	if reuseSession {
+		sess, err := a.store.Load(username)
+		switch {
+		case err == nil:
+			a.client.SetSession(sess)
+			validateErr := a.validateSession(ctx)
+			if validateErr == nil {
+				a.session = sess
+				logger.Log.Infow("Reusing stored session",
+					"account", a.config.Name,
+					"path", a.store.Path(username),
+					"age", time.Since(sess.CreatedAt).Round(time.Second),
+				)
+				return nil
+			}
+			logger.Log.Infof("Stored session is no longer valid, logging in again: %v", validateErr)
+
+			// Start over with an empty jar so stale cookies are not sent along with the login request
+			if err := a.newClient(creds); err != nil {
+				return err
+			}
+		case errors.Is(err, session.ErrNotFound):
+			logger.Log.Debugf("No stored session found at %s", a.store.Path(username))
+		default:
+			logger.Log.Warnf("Ignoring stored session: %v", err)
+		}
 	}
 
-	ctx := context.Background()
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.GetAPILoginURL(), bytes.NewBuffer(jsonLoginData))
+	sess, err := a.passwordLogin(ctx, creds)
 	if err != nil {
-		return fmt.Errorf("failed to create login request: %w", err)
+		return err
 	}
+	a.session = sess
 
-	req.Header.Set("Content-Type", "application/json")
+	if err := a.store.Save(sess); err != nil {
+		logger.Log.Warnf("Failed to persist session: %v", err)
+	} else {
+		logger.Log.Debugf("Session saved to %s", a.store.Path(username))
+	}
 
-	logger.Log.Debugw("Sending login request",
-		"url", config.GetAPILoginURL(),
-		"username", username,
-	)
+	logger.Log.Infof("Successfully logged in to account %s", a.config.Name)
+
+	return nil
+}
 
-	resp, err := client.Do(req)
+// newClient creates the account's HTTP client, which logs in again whenever the session expires
+func (a *account) newClient(creds *credentials.Chain) error {
+	jar, err := cookiejar.New(nil)
 	if err != nil {
-		return fmt.Errorf("failed to execute login request: %w", err)
+		return fmt.Errorf("failed to create cookie jar: %w", err)
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	transport, err := kidsnote.NewTransport(config.GetHTTPTransport())
 	if err != nil {
-		return fmt.Errorf("failed to read login response: %w", err)
+		return fmt.Errorf("invalid http configuration: %w", err)
 	}
 
-	var p fastjson.Parser
-	v, err := p.Parse(string(bodyBytes))
+	reauthTransport, err := session.NewTransport(transport, jar, a.relogin(creds), apiHosts())
 	if err != nil {
-		return fmt.Errorf("failed to parse login JSON response: %w", err)
+		return fmt.Errorf("invalid API URL configuration: %w", err)
 	}
 
-	if resp.StatusCode >= http.StatusBadRequest {
-		errCode := string(v.GetStringBytes("err_code"))
-		logger.Log.Errorw("Login failed",
-			"statusCode", resp.StatusCode,
-			"errCode", errCode,
-		)
-		return fmt.Errorf("login failed: %s", errCode)
+	userAgent := config.GetUserAgent()
+	if userAgent == "" {
+		userAgent = "kidsnoter/" + Version
 	}
 
-	userCookie := &http.Cookie{
-		Name:  "current_user",
-		Value: username,
+	client, err := kidsnote.NewClient(kidsnote.Config{
+		LoginURL:            config.GetAPILoginURL(),
+		LogoutURL:           config.GetAPILogoutURL(),
+		InfoURL:             config.GetAPIInfoURL(),
+		AlbumURL:            config.GetAPIAlbumURL(),
+		AlbumDetailURL:      config.GetAPIAlbumDetailURL(),
+		AlbumCommentURL:     config.GetAPIAlbumCommentURL(),
+		ReportURL:           config.GetAPIReportURL(),
+		CenterNoticeURL:     config.GetAPICenterNoticeURL(),
+		ClassNoticeURL:      config.GetAPIClassNoticeURL(),
+		MenuURL:             config.GetAPIMenuURL(),
+		CenterScheduleURL:   config.GetAPICenterScheduleURL(),
+		ClassScheduleURL:    config.GetAPIClassScheduleURL(),
+		UserCookieDomain:    config.GetUserCookieDomain(),
+		SessionCookieDomain: config.GetSessionCookieDomain(),
+		UserAgent:           userAgent,
+		APILimiter:          apiLimiter,
+		MediaLimiter:        mediaLimiter,
+		Strict:              strict || config.GetStrict(),
+		OnDecodeProblem:     listing.ReportDecodeProblem,
+		HTTPClient: &http.Client{
+			Jar:       jar,
+			Transport: reauthTransport,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
 	}
-	sessionIDCookie := &http.Cookie{
-		Name:  "session_id",
-		Value: string(v.GetStringBytes("session_id")),
+
+	a.client = client
+	a.lister = listing.NewLister(client, config.GetRetryPolicies().Listing, config.GetPageConcurrency())
+	return nil
+}
+
+// apiHosts returns the hosts of the configured API endpoints, whose auth failures mean the session expired
+func apiHosts() []string {
+	var hosts []string
+	for _, rawURL := range []string{
+		config.GetAPILoginURL(), config.GetAPIInfoURL(), config.GetAPIAlbumURL(), config.GetAPIAlbumDetailURL(),
+		config.GetAPIAlbumCommentURL(), config.GetAPIReportURL(), config.GetAPICenterNoticeURL(),
+		config.GetAPIClassNoticeURL(), config.GetAPIMenuURL(), config.GetAPICenterScheduleURL(),
+		config.GetAPIClassScheduleURL(),
+	} {
+		if parsed, err := url.Parse(rawURL); err == nil && parsed.Hostname() != "" && !slices.Contains(hosts, parsed.Hostname()) {
+			hosts = append(hosts, parsed.Hostname())
+		}
 	}
+	return hosts
+}
 
-	userCookieDomain, _ := url.Parse(config.GetUserCookieDomain())
-	sessionCookieDomain, _ := url.Parse(config.GetSessionCookieDomain())
-	client.Jar.SetCookies(userCookieDomain, []*http.Cookie{userCookie})
-	client.Jar.SetCookies(sessionCookieDomain, []*http.Cookie{sessionIDCookie})
+// relogin returns the login flow used to replace an expired session
+func (a *account) relogin(creds *credentials.Chain) session.LoginFunc {
+	return func(ctx context.Context) error {
+		logger.Log.Infof("Session expired for account %s, logging in again", a.config.Name)
 
-	isLoggedOn = true
+		sess, err := a.passwordLogin(ctx, creds)
+		if err != nil {
+			return err
+		}
+		a.session = sess
 
-	logger.Log.Info("Successfully logged in")
+		if err := a.store.Save(sess); err != nil {
+			logger.Log.Warnf("Failed to persist session: %v", err)
+		}
+		return nil
+	}
+}
 
-	return nil
+// credentialProviders returns the password sources in order of preference
+func (a *account) credentialProviders() []credentials.Provider {
+	return []credentials.Provider{
+		&credentials.Static{Password: a.config.Password},
+		&credentials.File{Path: a.config.PasswordFile},
+		&credentials.Command{Command: a.config.PasswordCommand},
+		&credentials.Prompt{In: os.Stdin, Out: os.Stderr},
+	}
+}
+
+// passwordLogin authenticates against the API with username and password, the client keeps the new session cookies
+func (a *account) passwordLogin(ctx context.Context, creds *credentials.Chain) (*session.Session, error) {
+	resolved, err := creds.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log.Infof("Using credentials from %s", resolved.Source)
+
+	logger.Log.Debugw("Sending login request",
+		"url", config.GetAPILoginURL(),
+		"username", resolved.Username,
+	)
+
+	var sess *session.Session
+	err = config.GetRetryPolicies().Login.Do(ctx, func() error {
+		var err error
+		sess, err = a.client.Login(ctx, resolved.Username, resolved.Password)
+		return err
+	})
+	if err != nil {
+		var apiErr *kidsnote.APIError
+		if errors.As(err, &apiErr) {
+			logger.Log.Errorw("Login failed",
+				"statusCode", apiErr.StatusCode,
+				"errCode", apiErr.Code,
+				"credentialSource", resolved.Source,
+			)
+		}
+		return nil, fmt.Errorf("login failed using credentials from %s: %w", resolved.Source, err)
+	}
+
+	return sess, nil
+}
+
+// validateSession checks that the cookies currently held by the client grant access to the API
+func (a *account) validateSession(ctx context.Context) error {
+	// A failed check falls back to a password login, there is no point in re-authenticating here
+	_, err := a.client.Me(session.WithoutReauth(ctx))
+	return err
 }
 
 // IsLoggedOn returns the login status
 func IsLoggedOn() bool {
-	return isLoggedOn
+	return len(accounts) > 0
 }
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/karolistamutis/kidsnoter/config"
+	"github.com/karolistamutis/kidsnoter/credentials"
+	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/karolistamutis/kidsnoter/session"
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Log out of Kidsnote and remove the stored session",
+	Long:  `This command invalidates the stored session on kidsnote.com and removes it from disk, so the next run logs in with your password again.`,
+	RunE:  runLogout,
+}
+
+func init() {
+	RootCmd.AddCommand(logoutCmd)
+}
+
+func runLogout(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	configs, err := configuredAccounts()
+	if err != nil {
+		return err
+	}
+
+	store, err := session.NewStore(config.GetSessionDir())
+	if err != nil {
+		return fmt.Errorf("failed to open session store: %w", err)
+	}
+
+	for _, cfg := range configs {
+		acc := &account{config: cfg, store: store}
+		if err := acc.logout(ctx); err != nil {
+			return fmt.Errorf("account %s: %w", cfg.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// logout invalidates the stored session on the server and removes it from the session store
+func (a *account) logout(ctx context.Context) error {
+	username := a.config.Username
+	if username == "" {
+		return fmt.Errorf("username is not set in configuration")
+	}
+
+	sess, err := a.store.Load(username)
+	if errors.Is(err, session.ErrNotFound) {
+		fmt.Printf("Account %s has no stored session\n", a.config.Name)
+		return nil
+	}
+	if err != nil {
+		logger.Log.Warnf("Removing unreadable session: %v", err)
+	} else {
+		if err := a.newClient(credentials.NewChain(username)); err != nil {
+			return err
+		}
+		a.client.SetSession(sess)
+
+		// The session is removed locally even if the server can't be told about it
+		if err := a.client.Logout(ctx); err != nil {
+			logger.Log.Warnf("Failed to invalidate session on the server: %v", err)
+		}
+	}
+
+	if err := a.store.Delete(username); err != nil {
+		return err
+	}
+
+	fmt.Printf("Logged out of account %s, removed %s\n", a.config.Name, a.store.Path(username))
+	return nil
+}
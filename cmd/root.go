@@ -15,9 +15,13 @@ var Version = "0.0.1"
 
 var (
 	verbosity int
+	strict    bool
 	ErrSilent = errors.New("SilentErr")
 )
 
+// annotationRequiresLogin marks commands that talk to kidsnote.com and need an authenticated client
+const annotationRequiresLogin = "kidsnoter/requires-login"
+
 var RootCmd = &cobra.Command{
 	Use:           "kidsnoter",
 	Short:         "Kidsnoter is a tool that lets you download all your kids' albums from kidsnote.com",
@@ -30,6 +34,8 @@ func init() {
 	RootCmd.SetFlagErrorFunc(flagErrorFunc)
 	RootCmd.PersistentPreRunE = loginPreRun
 	RootCmd.PersistentFlags().CountVarP(&verbosity, "verbosity", "v", "increase verbosity level")
+	RootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "fail on API responses with missing or invalid fields")
+	addAccountFlag(RootCmd)
 
 	cobra.OnInitialize(initConfig)
 }
@@ -43,6 +49,20 @@ func printVersion(ctx context.Context) error {
 	return nil
 }
 
+// requireLogin makes the command log in to the selected accounts before it runs
+func requireLogin(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[annotationRequiresLogin] = "true"
+	return cmd
+}
+
+// requiresLogin reports whether the command was marked with requireLogin
+func requiresLogin(cmd *cobra.Command) bool {
+	return cmd.Annotations[annotationRequiresLogin] == "true"
+}
+
 func flagErrorFunc(cmd *cobra.Command, err error) error {
 	cmd.Println(err)
 	cmd.Println(cmd.UsageString())
@@ -56,4 +76,5 @@ func initConfig() {
 		os.Exit(1)
 	}
 	logger.Configure(verbosity)
+	initRateLimiters()
 }
@@ -7,55 +7,75 @@ import (
 
 	"github.com/karolistamutis/kidsnoter/config"
 	"github.com/karolistamutis/kidsnoter/downloading"
-	"github.com/karolistamutis/kidsnoter/listing"
 	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/karolistamutis/kidsnoter/models"
 	"github.com/spf13/cobra"
 )
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
-	Short: "Run in continuous album synchronization mode",
+	Short: "Run in continuous album, daily report and notice synchronization mode",
 	RunE:  runServe,
 }
 
 func init() {
-	serveCmd.Flags().Bool("overwrite", false, "Overwrite existing files")
-	RootCmd.AddCommand(serveCmd)
+	addDownloadFlags(serveCmd)
+	RootCmd.AddCommand(requireLogin(serveCmd))
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
-	overwrite, err := cmd.Flags().GetBool("overwrite")
+	opts, err := getDownloadOptions(cmd)
 	if err != nil {
-		return fmt.Errorf("error getting overwrite flag: %w", err)
+		return err
 	}
+	defer opts.Files.Close()
 
-	return serveAlbums(ctx, overwrite)
+	return serveAlbums(ctx, opts)
 }
 
-func serveAlbums(ctx context.Context, overwrite bool) error {
-	lister := listing.NewLister(client)
-	albumDir := config.GetAlbumDir()
-	if albumDir == "" {
-		return fmt.Errorf("missing or empty invalid album_dir setting")
-	}
+// accountSync holds what's needed to synchronize the albums, reports and notices of one account
+type accountSync struct {
+	account    *account
+	downloader *downloading.Downloader
+	// children are those found by the latest sync, their calendars are exported after all accounts are synchronized
+	children []*models.Child
+}
 
+func serveAlbums(ctx context.Context, opts downloading.Options) error {
 	syncInterval := config.GetSyncInterval()
 	if syncInterval <= 0 {
 		return fmt.Errorf("invalid sync interval: %v, must be greater than 0", syncInterval)
 	}
 
-	downloader, err := downloading.NewDownloader(lister, client, overwrite)
-	if err != nil {
-		return fmt.Errorf("error creating downloader: %w", err)
+	syncs := make([]*accountSync, 0, len(accounts))
+	for _, acc := range accounts {
+		if acc.config.AlbumDir == "" {
+			return fmt.Errorf("missing or empty invalid album_dir setting for account %s", acc.config.Name)
+		}
+		removeStaleParts(acc.config.AlbumDir)
+
+		downloader, err := downloading.NewDownloader(acc.lister, acc.client, opts)
+		if err != nil {
+			return fmt.Errorf("error creating downloader: %w", err)
+		}
+		syncs = append(syncs, &accountSync{account: acc, downloader: downloader})
 	}
 
-	logger.Log.Infof("Starting continuous album synchronization with interval: %v", syncInterval)
-	logger.Log.Debugf("overwrite flag is: %v", overwrite)
+	logger.Log.Infof("Starting continuous album, report and notice synchronization of %d account(s) with interval: %v", len(syncs), syncInterval)
+	logger.Log.Debugf("overwrite flag is: %v, full flag is: %v", opts.Overwrite, opts.Full)
 
 	for {
-		if err := syncAllAlbums(ctx, lister, downloader, albumDir); err != nil {
-			logger.Log.Errorf("Error during synchronization: %v", err)
+		for _, s := range syncs {
+			if err := syncAccount(ctx, s); err != nil {
+				logger.Log.Errorf("Error during synchronization of account %s: %v", s.account.config.Name, err)
+			}
+		}
+
+		if config.GetCalendarExport() {
+			if err := exportCalendars(ctx, syncs); err != nil {
+				logger.Log.Errorf("Error exporting calendar: %v", err)
+			}
 		}
 
 		select {
@@ -66,18 +86,28 @@ func serveAlbums(ctx context.Context, overwrite bool) error {
 	}
 }
 
-func syncAllAlbums(ctx context.Context, lister listing.Lister, downloader *downloading.Downloader, albumDir string) error {
-	children, err := lister.ListChildren(ctx)
+func syncAccount(ctx context.Context, s *accountSync) error {
+	children, err := s.account.selectChildren(ctx, 0, "")
 	if err != nil {
-		return fmt.Errorf("error listing children: %w", err)
+		return err
 	}
+	s.children = children
 
 	var syncErrors []error
 	for _, child := range children {
-		if err := downloadAlbumsForChild(ctx, downloader, child, albumDir); err != nil {
+		if err := downloadAlbumsForChild(ctx, s.downloader, child, s.account.config.AlbumDir); err != nil {
 			logger.Log.Errorf("Error downloading albums for child %s: %v", child.Name, err)
 			syncErrors = append(syncErrors, err)
 		}
+		if err := downloadReportsForChild(ctx, s.downloader, child, s.account.config.AlbumDir); err != nil {
+			logger.Log.Errorf("Error downloading reports for child %s: %v", child.Name, err)
+			syncErrors = append(syncErrors, err)
+		}
+	}
+
+	if err := s.downloader.DownloadNotices(ctx, children, s.account.config.AlbumDir); err != nil {
+		logger.Log.Errorf("Error downloading notices: %v", err)
+		syncErrors = append(syncErrors, err)
 	}
 
 	if len(syncErrors) > 0 {
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/spf13/cobra"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the account you are logged in as",
+	Long:  `This command prints the user details kidsnote.com returns for your account, the linked children with their centers and classes, and where the session is stored.`,
+	RunE:  runWhoami,
+}
+
+func init() {
+	RootCmd.AddCommand(requireLogin(whoamiCmd))
+}
+
+func runWhoami(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	for i, acc := range accounts {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := acc.printWhoami(ctx); err != nil {
+			return fmt.Errorf("account %s: %w", acc.config.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *account) printWhoami(ctx context.Context) error {
+	profile, err := a.client.Me(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Account: %s\n", a.config.Name)
+	fmt.Printf("User: %s (%s), role: %s\n", profile.User.Name, a.config.Username, profile.User.Type)
+
+	fmt.Println("Children:")
+	for _, child := range profile.Children {
+		fmt.Printf("  %s [%d]\n", child.Name, child.ID)
+		for _, enrollment := range child.Enrollment {
+			fmt.Printf("    center: %s [%d], class: %s [%d]%s\n",
+				enrollment.CenterName, enrollment.CenterID, enrollment.ClassName, enrollment.ClassID, enrollmentPeriod(enrollment))
+		}
+	}
+
+	fmt.Printf("Session: %s, created %s (%s ago)\n",
+		a.store.Path(a.config.Username),
+		a.session.CreatedAt.Format(time.RFC3339),
+		time.Since(a.session.CreatedAt).Round(time.Second))
+
+	return nil
+}
+
+// enrollmentPeriod describes when the child was enrolled, if the API says so
+func enrollmentPeriod(enrollment kidsnote.Enrollment) string {
+	switch {
+	case enrollment.Start == "" && enrollment.End == "":
+		return ""
+	case enrollment.End == "":
+		return fmt.Sprintf(", since %s", enrollment.Start)
+	default:
+		return fmt.Sprintf(", %s to %s", enrollment.Start, enrollment.End)
+	}
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"errors"
 	"fmt"
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/karolistamutis/kidsnoter/retry"
 	"github.com/spf13/viper"
+	"strings"
 	"time"
 )
 
@@ -14,17 +17,54 @@ func InitConfig() error {
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("/config")
 	viper.SetEnvPrefix("KIDSNOTER")
+	// Nested settings such as http.proxy are read from e.g. KIDSNOTER_HTTP_PROXY
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	viper.AutomaticEnv()
 
 	// Set some defaults
-	const targetURL = "https://www.kidsnote.com/api"
-	viper.SetDefault("api.base_url", targetURL)
-	viper.SetDefault("api.login_url", targetURL+"/web/login")
-	viper.SetDefault("api.info_url", targetURL+"/v1/me/info")
-	viper.SetDefault("api.album_url", targetURL+"/v1_2/children/%d/albums")
-	viper.SetDefault("cookies.user_domain", "www.kidsnote.com")
-	viper.SetDefault("cookies.session_domain", ".kidsnote.com")
+	viper.SetDefault("api.base_url", kidsnote.DefaultBaseURL)
+	viper.SetDefault("api.login_url", kidsnote.DefaultLoginURL)
+	viper.SetDefault("api.logout_url", kidsnote.DefaultLogoutURL)
+	viper.SetDefault("api.info_url", kidsnote.DefaultInfoURL)
+	viper.SetDefault("api.album_url", kidsnote.DefaultAlbumURL)
+	viper.SetDefault("api.album_detail_url", kidsnote.DefaultAlbumDetailURL)
+	viper.SetDefault("api.album_comment_url", kidsnote.DefaultAlbumCommentURL)
+	viper.SetDefault("api.report_url", kidsnote.DefaultReportURL)
+	viper.SetDefault("api.center_notice_url", kidsnote.DefaultCenterNoticeURL)
+	viper.SetDefault("api.class_notice_url", kidsnote.DefaultClassNoticeURL)
+	viper.SetDefault("api.menu_url", kidsnote.DefaultMenuURL)
+	viper.SetDefault("api.center_schedule_url", kidsnote.DefaultCenterScheduleURL)
+	viper.SetDefault("api.class_schedule_url", kidsnote.DefaultClassScheduleURL)
+	viper.SetDefault("cookies.user_domain", kidsnote.DefaultUserCookieDomain)
+	viper.SetDefault("cookies.session_domain", kidsnote.DefaultSessionCookieDomain)
+	viper.SetDefault("session_dir", "~/.kidsnoter/sessions")
+	viper.SetDefault("http.connect_timeout", 30*time.Second)
+	viper.SetDefault("http.tls_handshake_timeout", 10*time.Second)
+	viper.SetDefault("http.response_header_timeout", time.Minute)
+	viper.SetDefault("http.read_timeout", 2*time.Minute)
+	viper.SetDefault("http.idle_conn_timeout", 90*time.Second)
+	viper.SetDefault("http.max_idle_conns_per_host", 10)
+	viper.SetDefault("http.proxy", "")
+	viper.SetDefault("http.ca_file", "")
+	viper.SetDefault("http.user_agent", "")
+	viper.SetDefault("rate_limit.api_per_second", 2)
+	viper.SetDefault("rate_limit.api_burst", 5)
+	viper.SetDefault("rate_limit.media_per_second", 5)
+	viper.SetDefault("rate_limit.media_burst", 10)
+	viper.SetDefault("listing.page_concurrency", 4)
+	viper.SetDefault("download.album_concurrency", 5)
+	viper.SetDefault("download.file_concurrency", 8)
+	viper.SetDefault("strict", false)
+	viper.SetDefault("sync.recheck_window", 14*24*time.Hour)
+	viper.SetDefault("calendar.export", false)
+	viper.SetDefault("calendar.file", "calendar.ics")
+	viper.SetDefault("calendar.months_back", 1)
+	viper.SetDefault("calendar.months_ahead", 1)
+	setRetryDefaults("login", 3, 2*time.Second, 30*time.Second)
+	setRetryDefaults("listing", 5, time.Second, time.Minute)
+	setRetryDefaults("image", 5, time.Second, time.Minute)
+	setRetryDefaults("video", 5, 2*time.Second, 2*time.Minute)
 
 	// Read the configuration file
 	if err := viper.ReadInConfig(); err != nil {
@@ -36,6 +76,12 @@ func InitConfig() error {
 	return nil
 }
 
+func setRetryDefaults(operation string, maxAttempts int, baseDelay, maxDelay time.Duration) {
+	viper.SetDefault("retry."+operation+".max_attempts", maxAttempts)
+	viper.SetDefault("retry."+operation+".base_delay", baseDelay)
+	viper.SetDefault("retry."+operation+".max_delay", maxDelay)
+}
+
 // GetString retrieves a string configuration value
 func GetString(key string) string {
 	return viper.GetString(key)
@@ -61,6 +107,11 @@ func GetAPILoginURL() string {
 	return viper.GetString("api.login_url")
 }
 
+// GetAPILogoutURL returns the logout URL for the API
+func GetAPILogoutURL() string {
+	return viper.GetString("api.logout_url")
+}
+
 // GetAPIInfoURL returns the profile info URL for the API
 func GetAPIInfoURL() string {
 	return viper.GetString("api.info_url")
@@ -71,6 +122,46 @@ func GetAPIAlbumURL() string {
 	return viper.GetString("api.album_url")
 }
 
+// GetAPIAlbumDetailURL returns the single album retrieval URL for the API
+func GetAPIAlbumDetailURL() string {
+	return viper.GetString("api.album_detail_url")
+}
+
+// GetAPIAlbumCommentURL returns the album comment retrieval URL for the API
+func GetAPIAlbumCommentURL() string {
+	return viper.GetString("api.album_comment_url")
+}
+
+// GetAPIReportURL returns the daily report retrieval URL for the API
+func GetAPIReportURL() string {
+	return viper.GetString("api.report_url")
+}
+
+// GetAPICenterNoticeURL returns the center notice retrieval URL for the API
+func GetAPICenterNoticeURL() string {
+	return viper.GetString("api.center_notice_url")
+}
+
+// GetAPIClassNoticeURL returns the class notice retrieval URL for the API
+func GetAPIClassNoticeURL() string {
+	return viper.GetString("api.class_notice_url")
+}
+
+// GetAPIMenuURL returns the center meal menu retrieval URL for the API
+func GetAPIMenuURL() string {
+	return viper.GetString("api.menu_url")
+}
+
+// GetAPICenterScheduleURL returns the center schedule retrieval URL for the API
+func GetAPICenterScheduleURL() string {
+	return viper.GetString("api.center_schedule_url")
+}
+
+// GetAPIClassScheduleURL returns the class schedule retrieval URL for the API
+func GetAPIClassScheduleURL() string {
+	return viper.GetString("api.class_schedule_url")
+}
+
 // GetUserCookieDomain returns the domain to be used for storing login related cookie data
 func GetUserCookieDomain() string {
 	return viper.GetString("cookies.user_domain")
@@ -81,6 +172,89 @@ func GetSessionCookieDomain() string {
 	return viper.GetString("cookies.session_domain")
 }
 
+// GetHTTPTransport returns the connection settings of the http section
+func GetHTTPTransport() kidsnote.TransportConfig {
+	return kidsnote.TransportConfig{
+		ConnectTimeout:        viper.GetDuration("http.connect_timeout"),
+		TLSHandshakeTimeout:   viper.GetDuration("http.tls_handshake_timeout"),
+		ResponseHeaderTimeout: viper.GetDuration("http.response_header_timeout"),
+		ReadTimeout:           viper.GetDuration("http.read_timeout"),
+		IdleConnTimeout:       viper.GetDuration("http.idle_conn_timeout"),
+		MaxIdleConnsPerHost:   viper.GetInt("http.max_idle_conns_per_host"),
+		ProxyURL:              viper.GetString("http.proxy"),
+		CAFile:                viper.GetString("http.ca_file"),
+	}
+}
+
+// GetUserAgent returns the User-Agent header sent with all requests
+func GetUserAgent() string {
+	return viper.GetString("http.user_agent")
+}
+
+// GetAPIRateLimit returns the average number of API calls per second and the burst size allowed over all accounts
+func GetAPIRateLimit() (float64, int) {
+	return viper.GetFloat64("rate_limit.api_per_second"), viper.GetInt("rate_limit.api_burst")
+}
+
+// GetMediaRateLimit returns the average number of photo and video downloads per second and the burst size allowed over all accounts
+func GetMediaRateLimit() (float64, int) {
+	return viper.GetFloat64("rate_limit.media_per_second"), viper.GetInt("rate_limit.media_burst")
+}
+
+// GetRetryPolicies returns the retry settings of every kind of operation
+func GetRetryPolicies() retry.Policies {
+	return retry.Policies{
+		Login:   getRetryPolicy("login"),
+		Listing: getRetryPolicy("listing"),
+		Image:   getRetryPolicy("image"),
+		Video:   getRetryPolicy("video"),
+	}
+}
+
+func getRetryPolicy(operation string) retry.Policy {
+	return retry.Policy{
+		Operation:   operation,
+		MaxAttempts: viper.GetInt("retry." + operation + ".max_attempts"),
+		BaseDelay:   viper.GetDuration("retry." + operation + ".base_delay"),
+		MaxDelay:    viper.GetDuration("retry." + operation + ".max_delay"),
+	}
+}
+
+// GetRecheckWindow returns how far before the newest synced album incremental syncs look for new comments
+func GetRecheckWindow() time.Duration {
+	return viper.GetDuration("sync.recheck_window")
+}
+
+// GetPageConcurrency returns the number of album pages of a child fetched at once
+func GetPageConcurrency() int {
+	return viper.GetInt("listing.page_concurrency")
+}
+
+// GetDownloadConcurrency returns the number of albums prepared and the number of files downloaded at once
+func GetDownloadConcurrency() (int, int) {
+	return viper.GetInt("download.album_concurrency"), viper.GetInt("download.file_concurrency")
+}
+
+// GetStrict returns whether responses that don't match the expected schema fail instead of being decoded leniently
+func GetStrict() bool {
+	return viper.GetBool("strict")
+}
+
+// GetCalendarExport returns whether serve keeps a calendar of menus and schedules up to date in the album directory
+func GetCalendarExport() bool {
+	return viper.GetBool("calendar.export")
+}
+
+// GetCalendarFile returns the path of the calendar file kept up to date by serve, relative to the album directory
+func GetCalendarFile() string {
+	return viper.GetString("calendar.file")
+}
+
+// GetCalendarMonths returns how many months before and after the current one are exported to the calendar
+func GetCalendarMonths() (int, int) {
+	return viper.GetInt("calendar.months_back"), viper.GetInt("calendar.months_ahead")
+}
+
 // GetUsername returns the logon username of the API
 func GetUsername() string {
 	return viper.GetString("username")
@@ -91,6 +265,74 @@ func GetPassword() string {
 	return viper.GetString("password")
 }
 
+// GetPasswordFile returns the path to a file holding the logon password of the API
+func GetPasswordFile() string {
+	return viper.GetString("password_file")
+}
+
+// GetPasswordCommand returns the command whose output is the logon password of the API
+func GetPasswordCommand() string {
+	return viper.GetString("password_command")
+}
+
+// GetSessionDir returns the path to the directory where login sessions are persisted
+func GetSessionDir() string { return viper.GetString("session_dir") }
+
+// Account holds the settings of a single Kidsnote account
+type Account struct {
+	Name            string   `mapstructure:"name"`
+	Username        string   `mapstructure:"username"`
+	Password        string   `mapstructure:"password"`
+	PasswordFile    string   `mapstructure:"password_file"`
+	PasswordCommand string   `mapstructure:"password_command"`
+	AlbumDir        string   `mapstructure:"album_dir"`
+	ChildIDs        []int    `mapstructure:"child_ids"`
+	ChildNames      []string `mapstructure:"child_names"`
+}
+
+// GetAccounts returns the configured accounts. Without an accounts list the top level
+// username, password and album_dir settings make up a single account.
+func GetAccounts() ([]*Account, error) {
+	if !viper.IsSet("accounts") {
+		return []*Account{{
+			Name:            GetUsername(),
+			Username:        GetUsername(),
+			Password:        GetPassword(),
+			PasswordFile:    GetPasswordFile(),
+			PasswordCommand: GetPasswordCommand(),
+			AlbumDir:        GetAlbumDir(),
+		}}, nil
+	}
+
+	var accounts []*Account
+	if err := viper.UnmarshalKey("accounts", &accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse accounts: %w", err)
+	}
+
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("accounts list is empty")
+	}
+
+	names := make(map[string]bool, len(accounts))
+	for i, account := range accounts {
+		if account.Username == "" {
+			return nil, fmt.Errorf("username is not set for account #%d", i+1)
+		}
+		if account.Name == "" {
+			account.Name = account.Username
+		}
+		if account.AlbumDir == "" {
+			account.AlbumDir = GetAlbumDir()
+		}
+		if names[account.Name] {
+			return nil, fmt.Errorf("duplicate account name %q", account.Name)
+		}
+		names[account.Name] = true
+	}
+
+	return accounts, nil
+}
+
 // GetAlbumDir returns the path to the directory root for saving downloaded albums
 func GetAlbumDir() string { return viper.GetString("album_dir") }
 
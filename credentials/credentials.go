@@ -0,0 +1,81 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnavailable is returned by a provider that has not been configured
+var ErrUnavailable = errors.New("credential source not configured")
+
+// Credentials holds the login details along with a description of where the password came from
+type Credentials struct {
+	Username string
+	Password string
+	Source   string
+}
+
+// Provider supplies the password for a given username, prompting for the username if needed
+type Provider interface {
+	// Name describes the source for logs and error messages
+	Name() string
+	// Credentials returns ErrUnavailable when the source is not configured
+	Credentials(ctx context.Context, username string) (*Credentials, error)
+}
+
+// interactive is implemented by providers that ask the user, which the chain asks only once
+type interactive interface {
+	Interactive() bool
+}
+
+// Chain resolves credentials from the first configured provider. Passwords from the configuration, a file or
+// a command are read again for every login, so a rotated secret is picked up; one typed in is remembered.
+type Chain struct {
+	username  string
+	providers []Provider
+
+	mu sync.Mutex
+	// prompted holds the credentials given to an interactive provider
+	prompted *Credentials
+}
+
+// NewChain creates a provider chain for username, providers are tried in the given order
+func NewChain(username string, providers ...Provider) *Chain {
+	return &Chain{
+		username:  username,
+		providers: providers,
+	}
+}
+
+// Resolve returns the credentials of the first configured provider. A provider that is configured but fails
+// is reported as an error rather than silently falling through to the next one.
+func (c *Chain) Resolve(ctx context.Context) (*Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.prompted != nil {
+		return c.prompted, nil
+	}
+
+	for _, provider := range c.providers {
+		creds, err := provider.Credentials(ctx, c.username)
+		if errors.Is(err, ErrUnavailable) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials from %s: %w", provider.Name(), err)
+		}
+		if creds.Username == "" || creds.Password == "" {
+			return nil, fmt.Errorf("%s returned an empty username or password", provider.Name())
+		}
+		creds.Source = provider.Name()
+		if p, ok := provider.(interactive); ok && p.Interactive() {
+			c.prompted = creds
+		}
+		return creds, nil
+	}
+
+	return nil, fmt.Errorf("no credentials found, set password, password_file or password_command in configuration")
+}
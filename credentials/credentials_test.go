@@ -0,0 +1,120 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeProvider returns the given password, or err, and counts how often it was asked
+type fakeProvider struct {
+	name     string
+	password string
+	err      error
+	calls    int
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Credentials(ctx context.Context, username string) (*Credentials, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Credentials{Username: username, Password: p.password}, nil
+}
+
+// promptProvider is a fakeProvider that asks the user
+type promptProvider struct {
+	fakeProvider
+}
+
+func (p *promptProvider) Interactive() bool { return true }
+
+func TestChainResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		providers  []Provider
+		wantSource string
+		wantErr    string
+	}{
+		{
+			name:       "first configured provider wins",
+			providers:  []Provider{&fakeProvider{name: "a", err: ErrUnavailable}, &fakeProvider{name: "b", password: "pw"}, &fakeProvider{name: "c", password: "other"}},
+			wantSource: "b",
+		},
+		{
+			name:      "configured provider failing doesn't fall through",
+			providers: []Provider{&fakeProvider{name: "a", err: errors.New("permission denied")}, &fakeProvider{name: "b", password: "pw"}},
+			wantErr:   "failed to read credentials from a",
+		},
+		{
+			name:      "empty password",
+			providers: []Provider{&fakeProvider{name: "a"}},
+			wantErr:   "empty username or password",
+		},
+		{
+			name:      "nothing configured",
+			providers: []Provider{&fakeProvider{name: "a", err: ErrUnavailable}},
+			wantErr:   "no credentials found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := NewChain("parent", tt.providers...).Resolve(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if creds.Source != tt.wantSource || creds.Username != "parent" {
+				t.Errorf("got %s from %s, want parent from %s", creds.Username, creds.Source, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestChainRereadsSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	chain := NewChain("parent", &File{Path: path})
+
+	creds, err := chain.Resolve(context.Background())
+	if err != nil || creds.Password != "old" {
+		t.Fatalf("got %v, %v, want the password from the file", creds, err)
+	}
+
+	// The secret is rotated while serve runs
+	if err := os.WriteFile(path, []byte("new\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	creds, err = chain.Resolve(context.Background())
+	if err != nil || creds.Password != "new" {
+		t.Errorf("got %v, %v, want the rotated password", creds, err)
+	}
+}
+
+func TestChainRemembersPrompt(t *testing.T) {
+	prompt := &promptProvider{fakeProvider{name: "prompt", password: "typed"}}
+	chain := NewChain("parent", prompt)
+
+	for range 3 {
+		creds, err := chain.Resolve(context.Background())
+		if err != nil || creds.Password != "typed" {
+			t.Fatalf("got %v, %v, want the typed password", creds, err)
+		}
+	}
+	if prompt.calls != 1 {
+		t.Errorf("prompted %d times, want 1", prompt.calls)
+	}
+}
@@ -0,0 +1,129 @@
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/karolistamutis/kidsnoter/util"
+	"golang.org/x/term"
+)
+
+// Static provides a password set directly in the configuration or environment
+type Static struct {
+	Password string
+}
+
+func (s *Static) Name() string { return "config password" }
+
+func (s *Static) Credentials(ctx context.Context, username string) (*Credentials, error) {
+	if s.Password == "" || username == "" {
+		return nil, ErrUnavailable
+	}
+	return &Credentials{Username: username, Password: s.Password}, nil
+}
+
+// File reads the password from a file, e.g. a Docker secret mounted under /run/secrets
+type File struct {
+	Path string
+}
+
+func (f *File) Name() string { return fmt.Sprintf("password_file %s", f.Path) }
+
+func (f *File) Credentials(ctx context.Context, username string) (*Credentials, error) {
+	if f.Path == "" || username == "" {
+		return nil, ErrUnavailable
+	}
+
+	path, err := util.ExpandTilde(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand path: %w", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{Username: username, Password: firstLine(content)}, nil
+}
+
+// Command runs a helper such as `pass show kidsnote` and reads the password from the first line of its output
+type Command struct {
+	Command string
+}
+
+func (c *Command) Name() string { return fmt.Sprintf("password_command %q", c.Command) }
+
+func (c *Command) Credentials(ctx context.Context, username string) (*Credentials, error) {
+	if c.Command == "" || username == "" {
+		return nil, ErrUnavailable
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", c.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", c.Command)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	return &Credentials{Username: username, Password: firstLine(stdout.Bytes())}, nil
+}
+
+// Prompt asks for the missing username and the password on the terminal without echoing the password
+type Prompt struct {
+	In  *os.File
+	Out io.Writer
+}
+
+func (p *Prompt) Name() string { return "interactive prompt" }
+
+// Interactive tells the chain to remember the answers rather than ask again on every login
+func (p *Prompt) Interactive() bool { return true }
+
+func (p *Prompt) Credentials(ctx context.Context, username string) (*Credentials, error) {
+	fd := int(p.In.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, ErrUnavailable
+	}
+
+	if username == "" {
+		fmt.Fprint(p.Out, "Kidsnote username: ")
+		line, err := bufio.NewReader(p.In).ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read username: %w", err)
+		}
+		username = strings.TrimSpace(line)
+	}
+
+	fmt.Fprintf(p.Out, "Kidsnote password for %s: ", username)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(p.Out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+
+	return &Credentials{Username: username, Password: string(password)}, nil
+}
+
+func firstLine(content []byte) string {
+	line, _, _ := strings.Cut(string(content), "\n")
+	return strings.TrimRight(line, "\r")
+}
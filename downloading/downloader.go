@@ -3,24 +3,26 @@ package downloading
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/karolistamutis/kidsnoter/kidsnote"
 	"github.com/karolistamutis/kidsnoter/listing"
 	"github.com/karolistamutis/kidsnoter/logger"
 	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/retry"
+	"github.com/karolistamutis/kidsnoter/syncstate"
 	"github.com/karolistamutis/kidsnoter/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"html/template"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
 
-const concurrentDownloads = 5
-
 var (
 	downloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "downloads_total",
@@ -42,87 +44,213 @@ var (
 	}, []string{"type"})
 )
 
+// Options control what a Downloader fetches
+type Options struct {
+	// Overwrite downloads all files again and rewrites album descriptions, it implies Full
+	Overwrite bool
+	// Full lists all albums instead of stopping at the ones a previous sync finished
+	Full bool
+	// Filter limits downloads to some albums or reports. Such a partial download neither uses nor moves the mark
+	// of the last finished sync.
+	Filter listing.ListOptions
+	// Retry holds the policies for failed image and video downloads and for fetching comments
+	Retry retry.Policies
+	// RecheckWindow makes incremental syncs list albums and reports created up to this long before the newest
+	// one of the last finished sync again, to pick up comments and replies posted since
+	RecheckWindow time.Duration
+	// AlbumConcurrency is the number of albums, reports or notices prepared at once, i.e. their comments
+	// fetched and descriptions written
+	AlbumConcurrency int
+	// Files is the pool downloading photos, videos and attachments, shared by all albums and all Downloaders
+	// given the same pool. It is required.
+	Files *FilePool
+}
+
 type Downloader struct {
 	lister    listing.Lister
-	client    *http.Client
+	client    *kidsnote.Client
+	retry     retry.Policies
 	overwrite bool
-	tmpl      *template.Template
+	full      bool
+	filter    listing.ListOptions
+	recheck   time.Duration
+	// albumWorkers bounds the albums, reports or notices being prepared, files is the pool downloading their
+	// photos, videos and attachments, shared by all of them
+	albumWorkers int
+	files        *FilePool
+	tmpl         *template.Template
+	// reportTmpl and noticeTmpl render the markdown files of daily reports and notices
+	reportTmpl *template.Template
+	noticeTmpl *template.Template
+}
+
+// templateFuncs are the functions available to the markdown templates
+var templateFuncs = template.FuncMap{
+	// lines splits text into its lines, e.g. to quote every line of a reply
+	"lines": func(text string) []string {
+		return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	},
 }
 
 // NewDownloader creates a new Downloader instance
-func NewDownloader(lister listing.Lister, client *http.Client, overwrite bool) (*Downloader, error) {
-	tmpl, err := template.ParseFiles("templates/album.md.tmpl")
+func NewDownloader(lister listing.Lister, client *kidsnote.Client, opts Options) (*Downloader, error) {
+	if opts.Files == nil {
+		return nil, fmt.Errorf("no file pool to download with")
+	}
+
+	tmpl, err := template.New("album.md.tmpl").Funcs(templateFuncs).ParseFiles("templates/album.md.tmpl")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
 
+	reportTmpl, err := template.ParseFiles("templates/report.md.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report template: %w", err)
+	}
+
+	noticeTmpl, err := template.ParseFiles("templates/notice.md.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse notice template: %w", err)
+	}
+
 	return &Downloader{
-		lister:    lister,
-		client:    client,
-		overwrite: overwrite,
-		tmpl:      tmpl,
+		lister:       lister,
+		client:       client,
+		retry:        opts.Retry,
+		overwrite:    opts.Overwrite,
+		full:         opts.Full || opts.Overwrite,
+		filter:       opts.Filter,
+		recheck:      opts.RecheckWindow,
+		albumWorkers: max(opts.AlbumConcurrency, 1),
+		files:        opts.Files,
+		tmpl:         tmpl,
+		reportTmpl:   reportTmpl,
+		noticeTmpl:   noticeTmpl,
 	}, nil
 }
 
-// DownloadAlbums downloads all albums for a given child
+// DownloadAlbums downloads the albums of a given child that are newer than the last finished sync,
+// or all of them when the downloader is set to Full
 func (d *Downloader) DownloadAlbums(ctx context.Context, childID int, outputDir string) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Hour)
 	defer cancel()
 
-	albumChan := make(chan *models.Album)
-	errChan := make(chan error, 1)
-	var wg sync.WaitGroup
+	state, err := syncstate.NewStore(outputDir, syncstate.Albums)
+	if err != nil {
+		return fmt.Errorf("failed to open sync state: %w", err)
+	}
+
+	listOpts, partial := d.listOptions(state, childID, syncstate.Albums)
 
-	go func() {
-		defer close(albumChan)
-		if err := d.lister.ListAlbums(ctx, childID, albumChan); err != nil {
-			errChan <- err
-		}
-	}()
-
-	semaphore := make(chan struct{}, concurrentDownloads)
-	var downloadErrs []error
-	mu := &sync.Mutex{}
-
-	totalAlbums := 0
-	successfulAlbums := 0
-
-	for album := range albumChan {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			totalAlbums++
-			wg.Add(1)
-			go func(a *models.Album) {
-				defer wg.Done()
-				semaphore <- struct{}{}
-				defer func() { <-semaphore }()
-
-				if err := d.downloadAlbum(ctx, a, outputDir); err != nil {
-					mu.Lock()
-					downloadErrs = append(downloadErrs, err)
-					downloadErrors.WithLabelValues("album").Inc()
-					mu.Unlock()
-				} else {
-					successfulAlbums++
-					downloadsTotal.WithLabelValues("album").Inc()
-				}
-			}(album)
+	marks := &markTracker{childID: childID}
+	var listErr error
+	err = runWorkers(d.albumWorkers, "album", func(albumChan chan<- *models.Album) error {
+		albums := d.lister.Albums(ctx, childID, &listOpts)
+		defer albums.Close()
+
+		for albums.Next() {
+			albumChan <- albums.Album()
 		}
+		listErr = albums.Err()
+		return listErr
+	}, func(album *models.Album) error {
+		err := d.downloadAlbum(ctx, album, outputDir)
+		marks.track(album.ID, album.Date, err)
+		return err
+	})
+
+	// Failed albums hold the mark back so they are listed again next time. Without a complete listing
+	// it's unknown which albums were missed, so the mark stays.
+	if !partial && listErr == nil {
+		saveMark(state, marks.mark())
 	}
 
-	wg.Wait()
-	close(errChan)
+	if err != nil {
+		return fmt.Errorf("encountered errors during download: %w", err)
+	}
 
-	for err := range errChan {
-		downloadErrs = append(downloadErrs, err)
+	return nil
+}
+
+// listOptions returns the downloader's filter, limited to the albums or reports created after the last finished
+// sync, less the recheck window, unless the downloader is set to Full. It also reports whether the filter selects just some of them.
+func (d *Downloader) listOptions(state *syncstate.Store, childID int, kind string) (listing.ListOptions, bool) {
+	listOpts := d.filter
+	partial := listOpts.Filtered()
+	if d.full || partial {
+		return listOpts, partial
 	}
 
-	if len(downloadErrs) > 0 {
-		return fmt.Errorf("encountered errors during download: %v", downloadErrs)
+	mark, err := state.Load(childID)
+	switch {
+	case err == nil:
+		listOpts.After = mark.Created.Add(-d.recheck)
+		logger.Log.Infof("Listing %s of child ID %d created after %s, %s before the newest one of the last sync", kind, childID, listOpts.After, d.recheck)
+	case errors.Is(err, syncstate.ErrNotFound):
+		logger.Log.Debugf("No sync state found at %s, listing all %s", state.Path(childID), kind)
+	default:
+		logger.Log.Warnf("Ignoring sync state, listing all %s: %v", kind, err)
 	}
-	return nil
+
+	return listOpts, false
+}
+
+func saveMark(state *syncstate.Store, newest *syncstate.Mark) {
+	if newest == nil {
+		return
+	}
+
+	newest.SyncedAt = time.Now()
+	if err := state.Save(newest); err != nil {
+		logger.Log.Warnf("Failed to save sync state: %v", err)
+	}
+}
+
+// markTracker finds how far a sync can move the mark: up to the newest album or report older than every
+// one that failed, so the failed ones are listed again next time. Files gone for good don't count as failures.
+type markTracker struct {
+	childID int
+
+	mu           sync.Mutex
+	done         []*syncstate.Mark
+	oldestFailed time.Time
+}
+
+// track records the outcome of downloading the album or report created at date
+func (t *markTracker) track(id int, date string, err error) {
+	created, dateErr := util.ParseAlbumDate(date)
+	if dateErr != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var gone *goneFilesError
+	if err != nil && !errors.As(err, &gone) {
+		if t.oldestFailed.IsZero() || created.Before(t.oldestFailed) {
+			t.oldestFailed = created
+		}
+		return
+	}
+	t.done = append(t.done, &syncstate.Mark{ChildID: t.childID, ID: id, Created: created})
+}
+
+// mark returns the mark to save, nil if there is none
+func (t *markTracker) mark() *syncstate.Mark {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var newest *syncstate.Mark
+	for _, mark := range t.done {
+		if !t.oldestFailed.IsZero() && !mark.Created.Before(t.oldestFailed) {
+			continue
+		}
+		if newest == nil || mark.Created.After(newest.Created) {
+			newest = mark
+		}
+	}
+	return newest
 }
 
 func (d *Downloader) downloadAlbum(ctx context.Context, album *models.Album, outputDir string) error {
@@ -148,38 +276,37 @@ func (d *Downloader) downloadAlbum(ctx context.Context, album *models.Album, out
 		// TODO new albums
 	}
 
-	if err := d.writeAlbumMetadata(album, albumDir); err != nil {
+	if err := d.writeAlbumMetadata(ctx, album, albumDir); err != nil {
 		return fmt.Errorf("failed to write album metadata: %w", err)
 	}
 
-	for _, image := range album.Images {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if err := d.downloadImage(ctx, image, albumDir); err != nil {
-				logger.Log.Errorf("Error downloading image %s, %v", image.DownloadLink, err)
-				downloadErrors.WithLabelValues("image").Inc()
-			} else {
-				downloadsTotal.WithLabelValues("image").Inc()
-			}
-		}
+	batch := d.files.batch()
+	err = d.downloadMedia(ctx, batch, album.Images, album.Video, albumDir)
+	filesErr := batch.wait(fmt.Sprintf("album %q", album.Title))
+	if err != nil {
+		return err
 	}
 
-	if album.Video != nil {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if err := d.downloadVideo(ctx, album.Video, albumDir); err != nil {
-				logger.Log.Errorf("Error downloading video for album %d: %v", album.ID, err)
-				downloadErrors.WithLabelValues("video").Inc()
-			} else {
-				downloadsTotal.WithLabelValues("video").Inc()
-			}
+	return filesErr
+}
+
+// downloadMedia submits the images and the video of an album, report or notice to the batch, to be downloaded to dir.
+// It fails only when ctx is done, the files that failed are counted by the batch.
+func (d *Downloader) downloadMedia(ctx context.Context, batch *fileBatch, images []*models.Image, video *models.Video, dir string) error {
+	for _, image := range images {
+		if err := batch.add(ctx, "image", image.DownloadLink, func() error {
+			return d.downloadImage(ctx, image, dir)
+		}); err != nil {
+			return err
 		}
 	}
 
+	if video != nil {
+		return batch.add(ctx, "video", video.DownloadLink, func() error {
+			return d.downloadVideo(ctx, video, dir)
+		})
+	}
+
 	return nil
 }
 
@@ -193,7 +320,7 @@ func (d *Downloader) downloadImage(ctx context.Context, image *models.Image, alb
 		logger.Log.Infof("Skipping image %s: File already exists and matches size", fileName)
 		return nil
 	}
-	return util.RetryWithBackoff(ctx, func() error {
+	return d.retry.Image.Do(ctx, func() error {
 		return d.downloadFile(ctx, image.DownloadLink, fileName, image.FileSize, "image")
 	})
 }
@@ -208,7 +335,7 @@ func (d *Downloader) downloadVideo(ctx context.Context, video *models.Video, alb
 		logger.Log.Infof("Skipping video %s: File already exists and matches size", videoFileName)
 		return nil
 	}
-	return util.RetryWithBackoff(ctx, func() error {
+	return d.retry.Video.Do(ctx, func() error {
 		return d.downloadFile(ctx, video.DownloadLink, videoFileName, video.FileSize, "video")
 	})
 }
@@ -226,30 +353,26 @@ func (d *Downloader) downloadFile(ctx context.Context, url, filepath string, exp
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	body, err := d.client.Download(ctx, url)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return fmt.Errorf("failed to download file: %w", err)
 	}
+	defer body.Close()
 
-	resp, err := d.client.Do(req)
+	// The file only shows up under its name once complete, an interrupted download leaves a .part file behind
+	out, err := util.CreateAtomic(filepath)
 	if err != nil {
-		return fmt.Errorf("failed to download file: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
+		return fmt.Errorf("failed to create output file: %v", err)
 	}
+	defer out.Abort()
 
-	out, err := os.Create(filepath)
+	_, err = io.Copy(out, body)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
+		return fmt.Errorf("failed to save file: %w", err)
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to save file: %v", err)
+	if err := out.Commit(); err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
 	}
 
 	downloadSize.WithLabelValues(fileType).Observe(float64(expectedSize))
@@ -257,59 +380,115 @@ func (d *Downloader) downloadFile(ctx context.Context, url, filepath string, exp
 	return nil
 }
 
-func (d *Downloader) writeAlbumMetadata(album *models.Album, albumDir string) error {
+// albumCommentsHeading starts the comments section, the last one of an album's description file
+const albumCommentsHeading = "\n\n## Comments\n"
+
+// writeAlbumMetadata writes the album's description file unless it is up to date, so new comments and edits
+// of the title or description are picked up. Comments are only fetched if the album has some and the file
+// doesn't have them yet, which the comment count recorded in its front matter tells.
+func (d *Downloader) writeAlbumMetadata(ctx context.Context, album *models.Album, albumDir string) error {
 	descriptionFile := filepath.Join(albumDir, "description.md")
 
-	// Check if the metadata file exists
-	if _, err := os.Stat(descriptionFile); os.IsNotExist(err) {
-		return d.createAlbumMetadata(descriptionFile, album)
+	content, err := d.renderAlbumMetadata(album)
+	if err != nil {
+		return err
 	}
 
-	// File exists, read and parse it
-	content, err := os.ReadFile(descriptionFile)
-	if err != nil {
-		return fmt.Errorf("failed to read album metadata file: %v", err)
+	if !d.overwrite {
+		// With the comment count unchanged, a file that only adds the comments section is up to date as well
+		existing, err := os.ReadFile(descriptionFile)
+		if err == nil && albumMetadataUpToDate(existing, content, album.CommentCount > 0) {
+			return nil
+		}
 	}
 
-	existingTitle, existingDate, err := util.ParseFrontmatter(string(content))
-	if err != nil {
-		// Something is wrong reading the frontmatter, just update the file
-		return d.createAlbumMetadata(descriptionFile, album)
+	if album.CommentCount > 0 {
+		comments, err := d.albumComments(ctx, album.ID)
+		if err != nil {
+			return err
+		}
+		album.Comments = comments
+
+		if content, err = d.renderAlbumMetadata(album); err != nil {
+			return err
+		}
 	}
 
-	// Check if update needed or overwrite is set
-	if existingTitle != album.Title || existingDate != album.Date || d.overwrite {
-		return d.createAlbumMetadata(descriptionFile, album)
+	return d.createAlbumMetadata(descriptionFile, content)
+}
+
+// albumMetadataUpToDate tells whether the existing description file matches the one rendered without comments,
+// apart from the comments section if withComments
+func albumMetadataUpToDate(existing, rendered []byte, withComments bool) bool {
+	if bytes.Equal(existing, rendered) {
+		return true
+	}
+	if !withComments {
+		return false
 	}
 
-	return nil
+	body := bytes.TrimSuffix(rendered, []byte("\n"))
+	return bytes.HasPrefix(existing, body) && bytes.HasPrefix(existing[len(body):], []byte(albumCommentsHeading))
 }
 
-func (d *Downloader) createAlbumMetadata(descriptionFile string, album *models.Album) error {
+func (d *Downloader) renderAlbumMetadata(album *models.Album) ([]byte, error) {
 	data := struct {
-		Title   string
-		Date    string
-		Content string
+		Title        string
+		Date         string
+		Content      string
+		Center       string
+		Class        string
+		LikeCount    int
+		CommentCount int
+		Comments     []*models.Comment
 	}{
-		Title:   album.Title,
-		Date:    album.Date,
-		Content: album.Content,
+		Title:        album.Title,
+		Date:         album.Date,
+		Content:      album.Content,
+		LikeCount:    album.LikeCount,
+		CommentCount: album.CommentCount,
+		Comments:     album.Comments,
+	}
+	if album.Enrollment != nil {
+		data.Center = album.Enrollment.CenterName
+		data.Class = album.Enrollment.ClassName
 	}
 
 	content := new(bytes.Buffer)
 	if err := d.tmpl.Execute(content, data); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+		return nil, fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	f, err := os.Create(descriptionFile)
-	if err != nil {
-		return fmt.Errorf("failed to create description file: %w", err)
-	}
-	defer f.Close()
+	return content.Bytes(), nil
+}
 
-	if err := os.WriteFile(descriptionFile, content.Bytes(), 0644); err != nil {
+func (d *Downloader) createAlbumMetadata(descriptionFile string, content []byte) error {
+	if err := util.WriteFileAtomic(descriptionFile, content); err != nil {
 		return fmt.Errorf("failed to write metadata file: %w", err)
 	}
 
 	return nil
 }
+
+// albumComments fetches all comments on the album
+func (d *Downloader) albumComments(ctx context.Context, albumID int) ([]*models.Comment, error) {
+	var comments []*models.Comment
+	pageOpts := &kidsnote.PageOptions{}
+	for {
+		var page *models.CommentPage
+		err := d.retry.Listing.Do(ctx, func() error {
+			var err error
+			page, err = d.client.AlbumComments(ctx, albumID, pageOpts)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get comments page %q of album ID %d: %w", pageOpts.Page, albumID, err)
+		}
+
+		comments = append(comments, page.Results...)
+		if page.Next == "" {
+			return comments, nil
+		}
+		pageOpts.Page = page.Next
+	}
+}
@@ -0,0 +1,154 @@
+package downloading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/karolistamutis/kidsnoter/models"
+)
+
+// TestMain runs the tests from the repository root, where the Downloader finds its templates
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestDownloader creates a Downloader whose client fetches album comments from commentsHandler
+func newTestDownloader(t *testing.T, commentsHandler http.Handler) *Downloader {
+	t.Helper()
+
+	srv := httptest.NewServer(commentsHandler)
+	t.Cleanup(srv.Close)
+
+	client, err := kidsnote.NewClient(kidsnote.Config{AlbumCommentURL: srv.URL + "/albums/%d/comments"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	files := NewFilePool(1)
+	t.Cleanup(files.Close)
+
+	d, err := NewDownloader(nil, client, Options{AlbumConcurrency: 1, Files: files})
+	if err != nil {
+		t.Fatalf("NewDownloader: %v", err)
+	}
+	return d
+}
+
+func TestRenderAlbumMetadataQuotesReplies(t *testing.T) {
+	d := newTestDownloader(t, http.NotFoundHandler())
+
+	content, err := d.renderAlbumMetadata(&models.Album{
+		Title: "Picnic",
+		Comments: []*models.Comment{{
+			AuthorName: "Teacher",
+			Content:    "What a day!",
+			Replies: []*models.Comment{
+				{AuthorName: "Mom", Content: "Thank you!\n\nSee you tomorrow,\r\nMom"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("renderAlbumMetadata: %v", err)
+	}
+
+	want := "> **Mom**\n>\n> Thank you!\n>\n> See you tomorrow,\n> Mom"
+	if !strings.Contains(string(content), want) {
+		t.Errorf("rendered reply isn't quoted line by line, want %q in:\n%s", want, content)
+	}
+}
+
+func TestWriteAlbumMetadataFetchesChangedComments(t *testing.T) {
+	var fetches atomic.Int32
+	d := newTestDownloader(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		fmt.Fprint(w, `{"count": 1, "next": null, "previous": null, "results": [{"id": 1, "author_name": "Teacher", "content": "Hi"}]}`)
+	}))
+	dir := t.TempDir()
+
+	steps := []struct {
+		name        string
+		album       models.Album
+		wantFetches int32
+	}{
+		{"no comments", models.Album{ID: 1, Title: "Picnic"}, 0},
+		{"first comment", models.Album{ID: 1, Title: "Picnic", CommentCount: 1}, 1},
+		{"unchanged", models.Album{ID: 1, Title: "Picnic", CommentCount: 1}, 1},
+		{"new like", models.Album{ID: 1, Title: "Picnic", CommentCount: 1, LikeCount: 2}, 2},
+		{"unchanged again", models.Album{ID: 1, Title: "Picnic", CommentCount: 1, LikeCount: 2}, 2},
+		{"new comment", models.Album{ID: 1, Title: "Picnic", CommentCount: 2, LikeCount: 2}, 3},
+	}
+
+	for _, step := range steps {
+		album := step.album
+		if err := d.writeAlbumMetadata(context.Background(), &album, dir); err != nil {
+			t.Fatalf("%s: writeAlbumMetadata: %v", step.name, err)
+		}
+		if n := fetches.Load(); n != step.wantFetches {
+			t.Errorf("%s: fetched comments %d times, want %d", step.name, n, step.wantFetches)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "description.md"))
+	if err != nil {
+		t.Fatalf("reading description: %v", err)
+	}
+	if !strings.Contains(string(content), "comments: 2") || !strings.Contains(string(content), "## Comments") {
+		t.Errorf("description lacks the comment count or the comments:\n%s", content)
+	}
+}
+
+func TestMarkTracker(t *testing.T) {
+	failed := errors.New("failed to download 1 file(s)")
+	gone := &goneFilesError{item: "album", count: 1}
+
+	type outcome struct {
+		id   int
+		date string
+		err  error
+	}
+	tests := []struct {
+		name     string
+		outcomes []outcome
+		wantID   int
+	}{
+		{"nothing synced", nil, 0},
+		{"newest", []outcome{{1, "2024-05-01T12:00:00.000000Z", nil}, {3, "2024-05-03T12:00:00.000000Z", nil}, {2, "2024-05-02T12:00:00.000000Z", nil}}, 3},
+		{"older than every failure", []outcome{{1, "2024-05-01T12:00:00.000000Z", nil}, {2, "2024-05-02T12:00:00.000000Z", failed}, {3, "2024-05-03T12:00:00.000000Z", nil}}, 1},
+		{"oldest failed", []outcome{{2, "2024-05-02T12:00:00.000000Z", failed}, {3, "2024-05-03T12:00:00.000000Z", nil}}, 0},
+		{"gone files don't hold back", []outcome{{1, "2024-05-01T12:00:00.000000Z", nil}, {2, "2024-05-02T12:00:00.000000Z", gone}}, 2},
+		{"unparsable date is skipped", []outcome{{1, "2024-05-01T12:00:00.000000Z", nil}, {2, "yesterday", failed}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &markTracker{childID: 7}
+			for _, o := range tt.outcomes {
+				tracker.track(o.id, o.date, o.err)
+			}
+
+			mark := tracker.mark()
+			if tt.wantID == 0 {
+				if mark != nil {
+					t.Errorf("got mark %+v, want none", mark)
+				}
+				return
+			}
+			if mark == nil || mark.ID != tt.wantID || mark.ChildID != 7 {
+				t.Errorf("got mark %+v, want album %d of child 7", mark, tt.wantID)
+			}
+		})
+	}
+}
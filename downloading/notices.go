@@ -0,0 +1,182 @@
+package downloading
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/util"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// noticeFile is the name of the markdown file written for every notice
+const noticeFile = "notice.md"
+
+// DownloadNotices downloads the notices of the centers and classes the given children are enrolled in, each board once
+// even if siblings share it. Notices are saved to the notices folder of outputDir along with their photos, video and
+// attached files. There are few notices and they may be edited later, so all of them are checked on every run.
+func (d *Downloader) DownloadNotices(ctx context.Context, children []*models.Child, outputDir string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Hour)
+	defer cancel()
+
+	var downloadErrs []error
+	for _, board := range noticeBoards(children) {
+		if err := d.downloadBoard(ctx, board, outputDir); err != nil {
+			downloadErrs = append(downloadErrs, err)
+		}
+	}
+
+	if len(downloadErrs) > 0 {
+		return fmt.Errorf("encountered errors during notice download: %w", errors.Join(downloadErrs...))
+	}
+
+	return nil
+}
+
+// noticeBoards returns the distinct centers and classes of the children
+func noticeBoards(children []*models.Child) []models.NoticeBoard {
+	var boards []models.NoticeBoard
+	seen := make(map[models.NoticeBoard]bool)
+	for _, child := range children {
+		if child.CenterID == 0 {
+			continue
+		}
+
+		childBoards := []models.NoticeBoard{{CenterID: child.CenterID, CenterName: child.CenterName}}
+		if child.ClassID != 0 {
+			childBoards = append(childBoards, models.NoticeBoard{
+				CenterID:   child.CenterID,
+				CenterName: child.CenterName,
+				ClassID:    child.ClassID,
+				ClassName:  child.ClassName,
+			})
+		}
+
+		for _, board := range childBoards {
+			if seen[board] {
+				continue
+			}
+			seen[board] = true
+			boards = append(boards, board)
+		}
+	}
+	return boards
+}
+
+func (d *Downloader) downloadBoard(ctx context.Context, board models.NoticeBoard, outputDir string) error {
+	listOpts := d.filter
+
+	return runWorkers(d.albumWorkers, "notice", func(noticeChan chan<- *models.Notice) error {
+		return d.lister.ListNotices(ctx, board, &listOpts, noticeChan)
+	}, func(notice *models.Notice) error {
+		return d.downloadNotice(ctx, notice, outputDir)
+	})
+}
+
+func (d *Downloader) downloadNotice(ctx context.Context, notice *models.Notice, outputDir string) error {
+	timer := prometheus.NewTimer(downloadDuration.WithLabelValues("notice"))
+	defer timer.ObserveDuration()
+
+	outputDir, err := util.ExpandTilde(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to expand output directory path: %w", err)
+	}
+
+	noticeDir := filepath.Join(outputDir, notice.GeneratedFolderName)
+
+	logger.Log.Infof("Downloading notice \"%s\" to directory %s", notice.Title, noticeDir)
+
+	if err := os.MkdirAll(noticeDir, 0755); err != nil {
+		return fmt.Errorf("failed to create notice directory: %w", err)
+	}
+
+	if err := d.writeNotice(notice, noticeDir); err != nil {
+		return fmt.Errorf("failed to write notice: %w", err)
+	}
+
+	batch := d.files.batch()
+	err = d.downloadMedia(ctx, batch, notice.Images, notice.Video, noticeDir)
+	if err == nil {
+		err = d.downloadAttachments(ctx, batch, notice.Files, noticeDir)
+	}
+
+	filesErr := batch.wait(fmt.Sprintf("notice %q", notice.Title))
+	if err != nil {
+		return err
+	}
+
+	return filesErr
+}
+
+// attachmentFileName returns the name an attachment is saved as, prefixed with its ID since
+// attachments of a notice may share the original name
+func attachmentFileName(file *models.Attachment) string {
+	name := filepath.Base(file.FileName)
+	if name == "." || name == string(filepath.Separator) {
+		name = "attachment"
+		if extension, err := util.ExtractExtensionFromURL(file.DownloadLink); err == nil && extension != "" {
+			name += "." + extension
+		}
+	}
+	return strconv.Itoa(file.ID) + "_" + name
+}
+
+// downloadAttachments submits the notice's attached files to the batch. It fails only when ctx is done.
+func (d *Downloader) downloadAttachments(ctx context.Context, batch *fileBatch, files []*models.Attachment, noticeDir string) error {
+	for _, file := range files {
+		if err := batch.add(ctx, "attachment", file.DownloadLink, func() error {
+			return d.downloadAttachment(ctx, file, noticeDir)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Downloader) downloadAttachment(ctx context.Context, file *models.Attachment, noticeDir string) error {
+	fileName := filepath.Join(noticeDir, attachmentFileName(file))
+	if !d.overwrite && util.FileExistsAndMatches(fileName, file.FileSize) {
+		logger.Log.Infof("Skipping attachment %s: File already exists and matches size", fileName)
+		return nil
+	}
+	// Attachments are documents and photos, retried like images
+	return d.retry.Image.Do(ctx, func() error {
+		return d.downloadFile(ctx, file.DownloadLink, fileName, file.FileSize, "attachment")
+	})
+}
+
+// writeNotice renders the notice's markdown file, leaving an existing file alone if the notice wasn't edited
+func (d *Downloader) writeNotice(notice *models.Notice, noticeDir string) error {
+	data := struct {
+		*models.Notice
+		Attachments []string
+	}{Notice: notice}
+	for _, file := range notice.Files {
+		data.Attachments = append(data.Attachments, attachmentFileName(file))
+	}
+
+	content := new(bytes.Buffer)
+	if err := d.noticeTmpl.Execute(content, data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	noticePath := filepath.Join(noticeDir, noticeFile)
+	if !d.overwrite {
+		if existing, err := os.ReadFile(noticePath); err == nil && bytes.Equal(existing, content.Bytes()) {
+			return nil
+		}
+	}
+
+	if err := util.WriteFileAtomic(noticePath, content.Bytes()); err != nil {
+		return fmt.Errorf("failed to write notice file: %w", err)
+	}
+
+	return nil
+}
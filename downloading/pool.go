@@ -0,0 +1,151 @@
+package downloading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/karolistamutis/kidsnoter/logger"
+)
+
+// FilePool downloads files with a fixed number of workers shared by all albums, reports or notices of a run,
+// across all the Downloaders given the pool. Submitting a file waits for a free worker, which holds back
+// the item workers and, through them, listing.
+type FilePool struct {
+	jobs chan func()
+	wg   sync.WaitGroup
+}
+
+// NewFilePool starts a pool downloading up to workers files at once, Close stops it
+func NewFilePool(workers int) *FilePool {
+	p := &FilePool{jobs: make(chan func())}
+	for range max(workers, 1) {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				job()
+			}
+		}()
+	}
+	return p
+}
+
+// Close stops the workers once the submitted files are done, the Downloaders using the pool must not be used afterwards
+func (p *FilePool) Close() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+// fileBatch tracks the files of a single album, report or notice submitted to a pool
+type fileBatch struct {
+	pool   *FilePool
+	wg     sync.WaitGroup
+	failed atomic.Int32
+	// gone counts the failed files the server no longer has, i.e. answered with 404 Not Found
+	gone atomic.Int32
+}
+
+// goneFilesError is the failure of an album, report or notice whose only failed files are gone for good.
+// Trying again won't bring them back, so it doesn't hold back the sync mark.
+type goneFilesError struct {
+	item  string
+	count int
+}
+
+func (e *goneFilesError) Error() string {
+	return fmt.Sprintf("%d file(s) of %s are no longer available", e.count, e.item)
+}
+
+func (p *FilePool) batch() *fileBatch {
+	return &fileBatch{pool: p}
+}
+
+// add submits the download of a file of the given type, e.g. image, waiting for a free worker.
+// It fails only when ctx is done before the file could be submitted.
+func (b *fileBatch) add(ctx context.Context, fileType, link string, download func() error) error {
+	b.wg.Add(1)
+	job := func() {
+		defer b.wg.Done()
+		if err := download(); err != nil {
+			logger.Log.Errorf("Error downloading %s %s, %v", fileType, link, err)
+			downloadErrors.WithLabelValues(fileType).Inc()
+			b.failed.Add(1)
+
+			// Other refusals, e.g. an expired link, may well succeed on a later run
+			if errors.Is(err, kidsnote.ErrNotFound) {
+				b.gone.Add(1)
+			}
+		} else {
+			downloadsTotal.WithLabelValues(fileType).Inc()
+		}
+	}
+
+	select {
+	case b.pool.jobs <- job:
+		return nil
+	case <-ctx.Done():
+		b.wg.Done()
+		return ctx.Err()
+	}
+}
+
+// wait waits for the submitted files and returns an error naming the item, e.g. album "Picnic", if any of them failed
+func (b *fileBatch) wait(item string) error {
+	b.wg.Wait()
+
+	failed, gone := b.failed.Load(), b.gone.Load()
+	switch {
+	case failed == 0:
+		return nil
+	case failed == gone:
+		return &goneFilesError{item: item, count: int(gone)}
+	default:
+		return fmt.Errorf("failed to download %d file(s) of %s", failed, item)
+	}
+}
+
+// runWorkers runs process on every item list sends, up to workers items at once. Items are handed over unbuffered,
+// so list only moves on to the next one once a worker is free. The failures are counted under kind and returned
+// joined along with the error of list.
+func runWorkers[T any](workers int, kind string, list func(chan<- T) error, process func(T) error) error {
+	items := make(chan T)
+
+	var mu sync.Mutex
+	var errs []error
+	var total, failed atomic.Int32
+
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				total.Add(1)
+				if err := process(item); err != nil {
+					failed.Add(1)
+					downloadErrors.WithLabelValues(kind).Inc()
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				} else {
+					downloadsTotal.WithLabelValues(kind).Inc()
+				}
+			}
+		}()
+	}
+
+	listErr := list(items)
+	close(items)
+	wg.Wait()
+
+	logger.Log.Infof("Downloaded %d of %d %s(s)", total.Load()-failed.Load(), total.Load(), kind)
+
+	if listErr != nil {
+		errs = append(errs, listErr)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,200 @@
+package downloading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+)
+
+func TestFileBatchGoneFiles(t *testing.T) {
+	notFound := &kidsnote.APIError{StatusCode: 404}
+	forbidden := &kidsnote.APIError{StatusCode: 403}
+
+	tests := []struct {
+		name     string
+		errs     []error
+		wantErr  bool
+		wantGone bool
+	}{
+		{"all downloaded", []error{nil, nil}, false, false},
+		{"only gone files failed", []error{nil, notFound, notFound}, true, true},
+		{"refused file isn't gone", []error{nil, forbidden}, true, false},
+		{"gone and refused files", []error{notFound, forbidden}, true, false},
+		{"network failure isn't gone", []error{errors.New("connection reset")}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewFilePool(2)
+			defer pool.Close()
+
+			batch := pool.batch()
+			for _, err := range tt.errs {
+				if addErr := batch.add(context.Background(), "image", "https://img/1.jpg", func() error { return err }); addErr != nil {
+					t.Fatalf("add: %v", addErr)
+				}
+			}
+
+			err := batch.wait(`album "Picnic"`)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want an error: %v", err, tt.wantErr)
+			}
+			var gone *goneFilesError
+			if errors.As(err, &gone) != tt.wantGone {
+				t.Errorf("got error %v, want files gone for good: %v", err, tt.wantGone)
+			}
+		})
+	}
+}
+
+func TestFilePoolLimitsConcurrency(t *testing.T) {
+	const workers = 3
+	pool := NewFilePool(workers)
+	defer pool.Close()
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+
+	// Files of several albums share the pool's workers
+	batches := []*fileBatch{pool.batch(), pool.batch()}
+	var submitted sync.WaitGroup
+	for i := range 10 {
+		submitted.Add(1)
+		go func() {
+			defer submitted.Done()
+			batches[i%len(batches)].add(context.Background(), "image", "https://img/1.jpg", func() error {
+				n := running.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				<-release
+				running.Add(-1)
+				return nil
+			})
+		}()
+	}
+
+	for running.Load() < workers {
+		time.Sleep(time.Millisecond)
+	}
+	// Give an extra worker, if there were one, the chance to pick up a file
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	submitted.Wait()
+
+	for _, batch := range batches {
+		if err := batch.wait("album"); err != nil {
+			t.Errorf("wait: %v", err)
+		}
+	}
+	if n := peak.Load(); n != workers {
+		t.Errorf("downloaded up to %d files at once, want %d", n, workers)
+	}
+}
+
+func TestFileBatchAddWaitsForWorker(t *testing.T) {
+	pool := NewFilePool(1)
+	defer pool.Close()
+
+	release := make(chan struct{})
+	batch := pool.batch()
+	if err := batch.add(context.Background(), "image", "https://img/1.jpg", func() error { <-release; return nil }); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	// The only worker is busy, so the next file can't be submitted
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ran := false
+	if err := batch.add(ctx, "image", "https://img/2.jpg", func() error { ran = true; return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want add to wait until ctx is done", err)
+	}
+
+	close(release)
+	if err := batch.wait("album"); err != nil {
+		t.Errorf("wait: %v", err)
+	}
+	if ran {
+		t.Error("file given up on was downloaded")
+	}
+}
+
+func TestRunWorkers(t *testing.T) {
+	const workers = 2
+	var running, peak atomic.Int32
+	listErr := errors.New("listing failed")
+
+	err := runWorkers(workers, "album", func(items chan<- int) error {
+		for i := range 6 {
+			items <- i
+		}
+		return listErr
+	}, func(i int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if i%3 == 0 {
+			return fmt.Errorf("item %d failed", i)
+		}
+		return nil
+	})
+
+	errs := 0
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		if !errors.Is(e, listErr) {
+			errs++
+		}
+	}
+	if errs != 2 || !errors.Is(err, listErr) {
+		t.Errorf("got error %v, want the failures of items 0 and 3 joined with the listing error", err)
+	}
+	if n := peak.Load(); n > workers {
+		t.Errorf("processed %d items at once, want at most %d", n, workers)
+	}
+}
+
+func TestRunWorkersHoldsBackListing(t *testing.T) {
+	release := make(chan struct{})
+	heldBack := false
+
+	err := runWorkers(1, "album", func(items chan<- int) error {
+		items <- 1
+		// The only worker is busy with the first item, so the second one isn't taken yet
+		select {
+		case items <- 2:
+			close(release)
+		case <-time.After(20 * time.Millisecond):
+			heldBack = true
+			close(release)
+			items <- 2
+		}
+		return nil
+	}, func(i int) error {
+		if i == 1 {
+			<-release
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("runWorkers: %v", err)
+	}
+	if !heldBack {
+		t.Error("listing moved on while every worker was busy")
+	}
+}
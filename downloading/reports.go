@@ -0,0 +1,109 @@
+package downloading
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/syncstate"
+	"github.com/karolistamutis/kidsnoter/util"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// reportFile is the name of the markdown file written for every daily report
+const reportFile = "report.md"
+
+// DownloadReports downloads the daily reports of a given child that are newer than the last finished sync,
+// or all of them when the downloader is set to Full. Every report is saved to its own folder next to the
+// child's albums, as a markdown file along with the attached photos and video.
+func (d *Downloader) DownloadReports(ctx context.Context, childID int, outputDir string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Hour)
+	defer cancel()
+
+	state, err := syncstate.NewStore(outputDir, syncstate.Reports)
+	if err != nil {
+		return fmt.Errorf("failed to open sync state: %w", err)
+	}
+
+	listOpts, partial := d.listOptions(state, childID, syncstate.Reports)
+
+	marks := &markTracker{childID: childID}
+	var listErr error
+	err = runWorkers(d.albumWorkers, "report", func(reportChan chan<- *models.Report) error {
+		listErr = d.lister.ListReports(ctx, childID, &listOpts, reportChan)
+		return listErr
+	}, func(report *models.Report) error {
+		err := d.downloadReport(ctx, report, outputDir)
+		marks.track(report.ID, report.Created, err)
+		return err
+	})
+
+	// As with albums, failed reports hold the mark back
+	if !partial && listErr == nil {
+		saveMark(state, marks.mark())
+	}
+
+	if err != nil {
+		return fmt.Errorf("encountered errors during report download: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Downloader) downloadReport(ctx context.Context, report *models.Report, outputDir string) error {
+	timer := prometheus.NewTimer(downloadDuration.WithLabelValues("report"))
+	defer timer.ObserveDuration()
+
+	outputDir, err := util.ExpandTilde(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to expand output directory path: %w", err)
+	}
+
+	reportDir := filepath.Join(outputDir, report.GeneratedFolderName)
+
+	logger.Log.Infof("Downloading report of %s to directory %s", report.Date, reportDir)
+
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	if err := d.writeReport(report, reportDir); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	batch := d.files.batch()
+	err = d.downloadMedia(ctx, batch, report.Images, report.Video, reportDir)
+	filesErr := batch.wait(fmt.Sprintf("report ID %d", report.ID))
+	if err != nil {
+		return err
+	}
+
+	return filesErr
+}
+
+// writeReport renders the report's markdown file, leaving an existing file alone if nothing changed,
+// e.g. no new replies were posted
+func (d *Downloader) writeReport(report *models.Report, reportDir string) error {
+	content := new(bytes.Buffer)
+	if err := d.reportTmpl.Execute(content, report); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	reportPath := filepath.Join(reportDir, reportFile)
+	if !d.overwrite {
+		if existing, err := os.ReadFile(reportPath); err == nil && bytes.Equal(existing, content.Bytes()) {
+			return nil
+		}
+	}
+
+	if err := util.WriteFileAtomic(reportPath, content.Bytes()); err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package kidsnote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/models"
+)
+
+// Profile is the logged on user's information returned by the info endpoint
+type Profile struct {
+	User     User           `json:"user"`
+	Children []ProfileChild `json:"children"`
+}
+
+// User describes the logged on user, Type is the role such as parent
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ProfileChild is a child linked to the user's account
+type ProfileChild struct {
+	ID          int          `json:"id"`
+	Name        string       `json:"name"`
+	Gender      string       `json:"gender"`
+	DateOfBirth string       `json:"date_birth"`
+	Enrollment  []Enrollment `json:"enrollment"`
+}
+
+// Enrollment places a child in a class of a center, Start and End are dates with End empty while enrolled
+type Enrollment struct {
+	CenterID   int    `json:"center_id"`
+	CenterName string `json:"center_name"`
+	ClassID    int    `json:"belong_to_class"`
+	ClassName  string `json:"class_name"`
+	Start      string `json:"date_start"`
+	End        string `json:"date_end"`
+}
+
+// PageOptions selects the page of a paged endpoint, such as albums or comments, to fetch
+type PageOptions struct {
+	// Page is the cursor from the Next field of the previous page, empty for the first page
+	Page string
+}
+
+// pageURL returns the URL of the page of a paged endpoint selected by opts
+func pageURL(rawURL string, opts *PageOptions) (*url.URL, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts != nil && opts.Page != "" {
+		q := parsed.Query()
+		q.Set("page", opts.Page)
+		parsed.RawQuery = q.Encode()
+	}
+
+	return parsed, nil
+}
+
+// Me returns the profile of the logged on user
+func (c *Client) Me(ctx context.Context) (*Profile, error) {
+	var profile Profile
+	if err := c.getJSON(ctx, c.cfg.InfoURL, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Children returns the children linked to the user's account along with their enrollment history.
+// Children who graduated have no current center and class.
+func (c *Client) Children(ctx context.Context) ([]*models.Child, error) {
+	profile, err := c.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.Children == nil {
+		return nil, fmt.Errorf("no children data found in JSON from URL %s", c.cfg.InfoURL)
+	}
+
+	now := time.Now()
+	children := make([]*models.Child, 0, len(profile.Children))
+	for _, child := range profile.Children {
+		modelChild := &models.Child{
+			ID:          child.ID,
+			Name:        child.Name,
+			Gender:      child.Gender,
+			DateOfBirth: child.DateOfBirth,
+		}
+
+		for _, enrollment := range child.Enrollment {
+			// Entries without a center or class don't tell where the child was
+			if enrollment.CenterID == 0 || enrollment.ClassID == 0 {
+				continue
+			}
+			modelChild.Enrollments = append(modelChild.Enrollments, &models.Enrollment{
+				CenterID:   enrollment.CenterID,
+				CenterName: enrollment.CenterName,
+				ClassID:    enrollment.ClassID,
+				ClassName:  enrollment.ClassName,
+				Start:      enrollment.Start,
+				End:        enrollment.End,
+			})
+		}
+
+		if current := modelChild.EnrollmentAt(now); current != nil {
+			modelChild.CenterID = current.CenterID
+			modelChild.CenterName = current.CenterName
+			modelChild.ClassID = current.ClassID
+			modelChild.ClassName = current.ClassName
+		}
+
+		children = append(children, modelChild)
+	}
+
+	return children, nil
+}
+
+// Albums returns a page of the child's albums, newest first
+func (c *Client) Albums(ctx context.Context, childID int, opts *PageOptions) (*models.AlbumPage, error) {
+	albumsURL, err := pageURL(fmt.Sprintf(c.cfg.AlbumURL, childID), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse album URL: %w", err)
+	}
+
+	var raw rawPage
+	if err := c.getJSON(ctx, albumsURL.String(), &raw); err != nil {
+		return nil, err
+	}
+
+	if raw.Results == nil {
+		return nil, fmt.Errorf("no album data found in JSON from URL %s", albumsURL)
+	}
+
+	page := &models.AlbumPage{
+		Count:    raw.Count,
+		Next:     raw.Next,
+		Previous: raw.Previous,
+		Results:  make([]*models.Album, 0, len(raw.Results)),
+		PageSize: len(raw.Results),
+	}
+
+	var problems []DecodeProblem
+	for _, item := range raw.Results {
+		album, albumProblems := decodeAlbum(item)
+		problems = append(problems, albumProblems...)
+		// Albums that couldn't be decoded at all are left out, the problems tell about them
+		if album != nil {
+			page.Results = append(page.Results, album)
+		}
+	}
+
+	if err := c.checkSchema(albumsURL.String(), problems); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Album returns a single album of the child, failing with ErrNotFound if the child has no such album
+func (c *Client) Album(ctx context.Context, childID, albumID int) (*models.Album, error) {
+	albumURL := fmt.Sprintf(c.cfg.AlbumDetailURL, childID, albumID)
+
+	var raw json.RawMessage
+	if err := c.getJSON(ctx, albumURL, &raw); err != nil {
+		return nil, err
+	}
+
+	album, problems := decodeAlbum(raw)
+	if err := c.checkSchema(albumURL, problems); err != nil {
+		return nil, err
+	}
+	if album == nil {
+		return nil, &SchemaError{URL: albumURL, Problems: problems}
+	}
+
+	return album, nil
+}
+
+// AlbumComments returns a page of the comments on an album along with their replies, oldest first
+func (c *Client) AlbumComments(ctx context.Context, albumID int, opts *PageOptions) (*models.CommentPage, error) {
+	commentsURL, err := pageURL(fmt.Sprintf(c.cfg.AlbumCommentURL, albumID), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse album comment URL: %w", err)
+	}
+
+	var page models.CommentPage
+	if err := c.getJSON(ctx, commentsURL.String(), &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// Reports returns a page of the child's daily reports, newest first
+func (c *Client) Reports(ctx context.Context, childID int, opts *PageOptions) (*models.ReportPage, error) {
+	reportsURL, err := pageURL(fmt.Sprintf(c.cfg.ReportURL, childID), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report URL: %w", err)
+	}
+
+	var page models.ReportPage
+	if err := c.getJSON(ctx, reportsURL.String(), &page); err != nil {
+		return nil, err
+	}
+
+	if page.Results == nil {
+		return nil, fmt.Errorf("no report data found in JSON from URL %s", reportsURL)
+	}
+
+	return &page, nil
+}
+
+// CenterNotices returns a page of the notices posted to the whole center, newest first
+func (c *Client) CenterNotices(ctx context.Context, centerID int, opts *PageOptions) (*models.NoticePage, error) {
+	return c.notices(ctx, fmt.Sprintf(c.cfg.CenterNoticeURL, centerID), opts)
+}
+
+// ClassNotices returns a page of the notices posted to a class, newest first
+func (c *Client) ClassNotices(ctx context.Context, classID int, opts *PageOptions) (*models.NoticePage, error) {
+	return c.notices(ctx, fmt.Sprintf(c.cfg.ClassNoticeURL, classID), opts)
+}
+
+func (c *Client) notices(ctx context.Context, rawURL string, opts *PageOptions) (*models.NoticePage, error) {
+	noticesURL, err := pageURL(rawURL, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse notice URL: %w", err)
+	}
+
+	var page models.NoticePage
+	if err := c.getJSON(ctx, noticesURL.String(), &page); err != nil {
+		return nil, err
+	}
+
+	if page.Results == nil {
+		return nil, fmt.Errorf("no notice data found in JSON from URL %s", noticesURL)
+	}
+
+	return &page, nil
+}
+
+// Menus returns the center's meal menus of the month the given time falls in
+func (c *Client) Menus(ctx context.Context, centerID int, month time.Time) ([]*models.Menu, error) {
+	var menus []*models.Menu
+	if err := c.getMonthly(ctx, fmt.Sprintf(c.cfg.MenuURL, centerID), month, &menus); err != nil {
+		return nil, err
+	}
+	return menus, nil
+}
+
+// CenterSchedule returns the events of the center's schedule in the month the given time falls in
+func (c *Client) CenterSchedule(ctx context.Context, centerID int, month time.Time) ([]*models.ScheduleEvent, error) {
+	var events []*models.ScheduleEvent
+	if err := c.getMonthly(ctx, fmt.Sprintf(c.cfg.CenterScheduleURL, centerID), month, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ClassSchedule returns the events of the class' schedule in the month the given time falls in
+func (c *Client) ClassSchedule(ctx context.Context, classID int, month time.Time) ([]*models.ScheduleEvent, error) {
+	var events []*models.ScheduleEvent
+	if err := c.getMonthly(ctx, fmt.Sprintf(c.cfg.ClassScheduleURL, classID), month, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// getMonthly fetches the results of a month from an endpoint taking the year and the month as query parameters
+func (c *Client) getMonthly(ctx context.Context, rawURL string, month time.Time, results any) error {
+	monthlyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	q := monthlyURL.Query()
+	q.Set("year", strconv.Itoa(month.Year()))
+	q.Set("month", strconv.Itoa(int(month.Month())))
+	monthlyURL.RawQuery = q.Encode()
+
+	page := struct {
+		Results any `json:"results"`
+	}{Results: results}
+	if err := c.getJSON(ctx, monthlyURL.String(), &page); err != nil {
+		return err
+	}
+
+	return nil
+}
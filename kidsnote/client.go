@@ -0,0 +1,294 @@
+package kidsnote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/session"
+)
+
+// Default kidsnote.com endpoints and cookie domains, used for the Config fields left empty
+const (
+	DefaultBaseURL             = "https://www.kidsnote.com/api"
+	DefaultLoginURL            = DefaultBaseURL + "/web/login"
+	DefaultLogoutURL           = DefaultBaseURL + "/web/logout"
+	DefaultInfoURL             = DefaultBaseURL + "/v1/me/info"
+	DefaultAlbumURL            = DefaultBaseURL + "/v1_2/children/%d/albums"
+	DefaultAlbumDetailURL      = DefaultBaseURL + "/v1_2/children/%d/albums/%d"
+	DefaultAlbumCommentURL     = DefaultBaseURL + "/v1/albums/%d/comments"
+	DefaultReportURL           = DefaultBaseURL + "/v1_2/children/%d/reports"
+	DefaultCenterNoticeURL     = DefaultBaseURL + "/v1/centers/%d/notices"
+	DefaultClassNoticeURL      = DefaultBaseURL + "/v1/classes/%d/notices"
+	DefaultMenuURL             = DefaultBaseURL + "/v1/centers/%d/menus"
+	DefaultCenterScheduleURL   = DefaultBaseURL + "/v1/centers/%d/schedules"
+	DefaultClassScheduleURL    = DefaultBaseURL + "/v1/classes/%d/schedules"
+	DefaultUserCookieDomain    = "www.kidsnote.com"
+	DefaultSessionCookieDomain = ".kidsnote.com"
+)
+
+// Config holds the endpoints and the HTTP client a Client talks to
+type Config struct {
+	LoginURL  string
+	LogoutURL string
+	InfoURL   string
+	// AlbumURL is a format string taking the child ID
+	AlbumURL string
+	// AlbumDetailURL is a format string taking the child ID and the album ID
+	AlbumDetailURL string
+	// AlbumCommentURL is a format string taking the album ID
+	AlbumCommentURL string
+	// ReportURL is a format string taking the child ID
+	ReportURL string
+	// CenterNoticeURL is a format string taking the center ID, ClassNoticeURL one taking the class ID
+	CenterNoticeURL string
+	ClassNoticeURL  string
+	// MenuURL and CenterScheduleURL are format strings taking the center ID, ClassScheduleURL one taking the class ID
+	MenuURL           string
+	CenterScheduleURL string
+	ClassScheduleURL  string
+
+	UserCookieDomain    string
+	SessionCookieDomain string
+
+	// UserAgent is sent with every request, Go's default is used if empty
+	UserAgent string
+
+	// APILimiter paces API calls and MediaLimiter paces photo and video downloads. Both are optional and
+	// may be shared between clients to limit the overall request rate.
+	APILimiter   Limiter
+	MediaLimiter Limiter
+
+	// Strict fails requests whose response doesn't match the models, instead of skipping albums
+	// that can't be decoded and going on with incomplete ones
+	Strict bool
+	// OnDecodeProblem is called for every mismatch between a response and the models, e.g. to log it
+	OnDecodeProblem func(DecodeProblem)
+
+	// HTTPClient sends all requests. A client without a cookie jar is copied and given one,
+	// since the session is kept in cookies.
+	HTTPClient *http.Client
+}
+
+// Limiter paces requests, see ratelimit.Limiter
+type Limiter interface {
+	// Wait blocks until a request may be sent or ctx is done
+	Wait(ctx context.Context) error
+	// Pause holds back requests for the given duration after the API asked to slow down
+	Pause(d time.Duration)
+}
+
+// Client is a Kidsnote API client holding the session of a single user
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+type loginRequest struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	RememberMe bool   `json:"remember_me"`
+}
+
+type loginResponse struct {
+	SessionID string `json:"session_id"`
+}
+
+// NewClient creates a client, filling in the kidsnote.com defaults for empty Config fields
+func NewClient(cfg Config) (*Client, error) {
+	cfg.LoginURL = withDefault(cfg.LoginURL, DefaultLoginURL)
+	cfg.LogoutURL = withDefault(cfg.LogoutURL, DefaultLogoutURL)
+	cfg.InfoURL = withDefault(cfg.InfoURL, DefaultInfoURL)
+	cfg.AlbumURL = withDefault(cfg.AlbumURL, DefaultAlbumURL)
+	cfg.AlbumDetailURL = withDefault(cfg.AlbumDetailURL, DefaultAlbumDetailURL)
+	cfg.AlbumCommentURL = withDefault(cfg.AlbumCommentURL, DefaultAlbumCommentURL)
+	cfg.ReportURL = withDefault(cfg.ReportURL, DefaultReportURL)
+	cfg.CenterNoticeURL = withDefault(cfg.CenterNoticeURL, DefaultCenterNoticeURL)
+	cfg.ClassNoticeURL = withDefault(cfg.ClassNoticeURL, DefaultClassNoticeURL)
+	cfg.MenuURL = withDefault(cfg.MenuURL, DefaultMenuURL)
+	cfg.CenterScheduleURL = withDefault(cfg.CenterScheduleURL, DefaultCenterScheduleURL)
+	cfg.ClassScheduleURL = withDefault(cfg.ClassScheduleURL, DefaultClassScheduleURL)
+	cfg.UserCookieDomain = withDefault(cfg.UserCookieDomain, DefaultUserCookieDomain)
+	cfg.SessionCookieDomain = withDefault(cfg.SessionCookieDomain, DefaultSessionCookieDomain)
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	if httpClient.Jar == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+		}
+		withJar := *httpClient
+		withJar.Jar = jar
+		httpClient = &withJar
+	}
+
+	return &Client{cfg: cfg, http: httpClient}, nil
+}
+
+// Login authenticates with username and password and keeps the new session cookies for the following requests.
+// The login request is never re-authenticated by a session.Transport.
+func (c *Client) Login(ctx context.Context, username, password string) (*session.Session, error) {
+	resp, err := c.do(session.WithoutReauth(ctx), c.cfg.APILimiter, http.MethodPost, c.cfg.LoginURL, loginRequest{
+		Username:   username,
+		Password:   password,
+		RememberMe: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var login loginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+		return nil, fmt.Errorf("failed to parse login JSON response: %w", err)
+	}
+	if login.SessionID == "" {
+		return nil, fmt.Errorf("login response from URL %s has no session ID", c.cfg.LoginURL)
+	}
+
+	sess := &session.Session{
+		Username:  username,
+		SessionID: login.SessionID,
+		CreatedAt: time.Now(),
+	}
+	c.SetSession(sess)
+
+	return sess, nil
+}
+
+// SetSession stores the cookies of a previously obtained session, e.g. one loaded from a session.Store
+func (c *Client) SetSession(sess *session.Session) {
+	userCookieDomain, _ := cookieURL(c.cfg.UserCookieDomain)
+	sessionCookieDomain, sessionDomain := cookieURL(c.cfg.SessionCookieDomain)
+
+	userCookie := &http.Cookie{
+		Name:  "current_user",
+		Value: sess.Username,
+	}
+	sessionIDCookie := &http.Cookie{
+		Name:   "session_id",
+		Value:  sess.SessionID,
+		Domain: sessionDomain,
+	}
+
+	c.http.Jar.SetCookies(userCookieDomain, []*http.Cookie{userCookie})
+	c.http.Jar.SetCookies(sessionCookieDomain, []*http.Cookie{sessionIDCookie})
+}
+
+// Logout ends the current session on the server
+func (c *Client) Logout(ctx context.Context) error {
+	resp, err := c.do(session.WithoutReauth(ctx), c.cfg.APILimiter, http.MethodPost, c.cfg.LogoutURL, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Download fetches a photo or video, the caller must close the returned body.
+// Media isn't behind the session, so its auth failures never make a session.Transport log in again.
+func (c *Client) Download(ctx context.Context, mediaURL string) (io.ReadCloser, error) {
+	resp, err := c.do(session.WithoutReauth(ctx), c.cfg.MediaLimiter, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// getJSON sends a GET request and decodes the JSON response into v
+func (c *Client) getJSON(ctx context.Context, requestURL string, v any) error {
+	resp, err := c.do(ctx, c.cfg.APILimiter, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// An expired session may get redirected to an HTML login page, which won't parse
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse JSON response from URL %s: %w", requestURL, err)
+	}
+	return nil
+}
+
+// do sends the request once limiter allows it, with body encoded as JSON if set, and turns non-successful
+// responses into an *APIError. A Retry-After response pauses the limiter.
+func (c *Client) do(ctx context.Context, limiter Limiter, method, requestURL string, body any) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		// A bytes.Reader body can be rewound, so session.Transport is able to replay the request
+		reqBody = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for URL %s: %w", requestURL, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.cfg.UserAgent != "" {
+		req.Header.Set("User-Agent", c.cfg.UserAgent)
+	}
+
+	if limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request for URL %s: %w", requestURL, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		apiErr := newAPIError(resp)
+		if limiter != nil && apiErr.retryAfter > 0 {
+			limiter.Pause(apiErr.retryAfter)
+		}
+		return nil, apiErr
+	}
+
+	return resp, nil
+}
+
+// cookieURL turns a cookie domain setting such as www.kidsnote.com or .kidsnote.com into a URL the cookie jar accepts.
+// The returned domain is set for leading dot settings, making the cookie valid for all subdomains.
+func cookieURL(setting string) (*url.URL, string) {
+	if !strings.Contains(setting, "://") {
+		setting = "https://" + setting
+	}
+
+	u, err := url.Parse(setting)
+	if err != nil {
+		return &url.URL{}, ""
+	}
+
+	if strings.HasPrefix(u.Host, ".") {
+		u.Host = strings.TrimPrefix(u.Host, ".")
+		return u, u.Host
+	}
+	return u, ""
+}
+
+func withDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,101 @@
+package kidsnote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultRetryAfter is the pause after a 429 response that doesn't say how long to wait
+const defaultRetryAfter = 30 * time.Second
+
+var (
+	// ErrUnauthorized is matched by API errors caused by a missing or expired session or rejected credentials
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound is matched by API errors for resources that don't exist, e.g. deleted media
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited is matched by API errors telling the client to slow down
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// APIError is returned for responses with a non-successful HTTP status
+type APIError struct {
+	StatusCode int
+	Status     string
+	URL        string
+	// Code is the err_code reported by the API, if any
+	Code string
+
+	retryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("received HTTP status %s from URL %s: %s", e.Status, e.URL, e.Code)
+	}
+	return fmt.Sprintf("received HTTP status %s from URL %s", e.Status, e.URL)
+}
+
+// Unwrap maps the HTTP status to ErrUnauthorized, ErrNotFound or ErrRateLimited
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusNotFound, http.StatusGone:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// RetryAfter returns how long the API asked to wait before trying again, zero if it didn't
+func (e *APIError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// newAPIError builds an APIError from the response, reading the err_code from a JSON body if there is one
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		URL:        resp.Request.URL.String(),
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		apiErr.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		if apiErr.retryAfter <= 0 {
+			apiErr.retryAfter = defaultRetryAfter
+		}
+	case http.StatusServiceUnavailable:
+		apiErr.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	var body struct {
+		ErrCode string `json:"err_code"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err == nil {
+		apiErr.Code = body.ErrCode
+	}
+
+	return apiErr
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
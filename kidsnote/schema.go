@@ -0,0 +1,237 @@
+package kidsnote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/util"
+)
+
+// Reasons of a DecodeProblem
+const (
+	// ProblemMissing is a required field that is absent or empty
+	ProblemMissing = "missing"
+	// ProblemInvalid is a field that has the wrong type or format
+	ProblemInvalid = "invalid"
+	// ProblemUnknown is a field the models don't have, e.g. one that was renamed or one kidsnoter doesn't use
+	ProblemUnknown = "unknown"
+)
+
+// DecodeProblem is a mismatch between an item of an API response and the models, a sign that the API changed
+type DecodeProblem struct {
+	// Item is the kind of item, e.g. album, and ID its ID if known
+	Item string
+	ID   int
+	// Field is the JSON path of the field within the item, e.g. attached_images[2].original.
+	// Unknown fields in lists leave out the index, e.g. attached_images[].thumbnail.
+	Field  string
+	Reason string
+	Err    error
+}
+
+func (p DecodeProblem) String() string {
+	s := fmt.Sprintf("%s field %q of %s", p.Reason, p.Field, p.Item)
+	if p.ID != 0 {
+		s += fmt.Sprintf(" ID %d", p.ID)
+	}
+	if p.Err != nil {
+		s += ": " + p.Err.Error()
+	}
+	return s
+}
+
+// SchemaError is returned in strict mode for a response with missing or invalid fields
+type SchemaError struct {
+	URL      string
+	Problems []DecodeProblem
+}
+
+func (e *SchemaError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = problem.String()
+	}
+	return fmt.Sprintf("response from %s doesn't match the expected schema: %s", e.URL, strings.Join(problems, "; "))
+}
+
+// rawPage is a page of results, each decoded on its own so one bad item doesn't spoil the page
+type rawPage struct {
+	Count    int               `json:"count"`
+	Next     string            `json:"next"`
+	Previous string            `json:"previous"`
+	Results  []json.RawMessage `json:"results"`
+}
+
+// checkSchema reports the problems to the OnDecodeProblem hook and, in strict mode, turns the missing and invalid
+// fields into an error
+func (c *Client) checkSchema(url string, problems []DecodeProblem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if c.cfg.OnDecodeProblem != nil {
+		for _, problem := range problems {
+			c.cfg.OnDecodeProblem(problem)
+		}
+	}
+
+	if !c.cfg.Strict {
+		return nil
+	}
+
+	// The models only declare the fields kidsnoter uses, so unknown ones don't break anything
+	var broken []DecodeProblem
+	for _, problem := range problems {
+		if problem.Reason != ProblemUnknown {
+			broken = append(broken, problem)
+		}
+	}
+	if len(broken) > 0 {
+		return &SchemaError{URL: url, Problems: broken}
+	}
+	return nil
+}
+
+// decodeAlbum decodes and validates an album. The album is nil if it couldn't be decoded at all.
+func decodeAlbum(raw json.RawMessage) (*models.Album, []DecodeProblem) {
+	var album models.Album
+	if err := json.Unmarshal(raw, &album); err != nil {
+		var id struct {
+			ID int `json:"id"`
+		}
+		_ = json.Unmarshal(raw, &id)
+
+		field := ""
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			field = typeErr.Field
+		}
+		return nil, []DecodeProblem{{Item: "album", ID: id.ID, Field: field, Reason: ProblemInvalid, Err: err}}
+	}
+
+	var problems []DecodeProblem
+	for _, field := range unknownFields(raw, reflect.TypeOf(album), "") {
+		problems = append(problems, DecodeProblem{Item: "album", ID: album.ID, Field: field, Reason: ProblemUnknown})
+	}
+
+	return &album, append(problems, validateAlbum(&album)...)
+}
+
+// validateAlbum checks the fields needed to list and download the album
+func validateAlbum(album *models.Album) []DecodeProblem {
+	var problems []DecodeProblem
+	problem := func(field, reason string, err error) {
+		problems = append(problems, DecodeProblem{Item: "album", ID: album.ID, Field: field, Reason: reason, Err: err})
+	}
+
+	if album.ID == 0 {
+		problem("id", ProblemMissing, nil)
+	}
+	if album.Date == "" {
+		problem("created", ProblemMissing, nil)
+	} else if _, err := util.ParseAlbumDate(album.Date); err != nil {
+		problem("created", ProblemInvalid, err)
+	}
+
+	for i, image := range album.Images {
+		if image == nil {
+			problem(fmt.Sprintf("attached_images[%d]", i), ProblemMissing, nil)
+			continue
+		}
+		if image.ID == 0 {
+			problem(fmt.Sprintf("attached_images[%d].id", i), ProblemMissing, nil)
+		}
+		if image.DownloadLink == "" {
+			problem(fmt.Sprintf("attached_images[%d].original", i), ProblemMissing, nil)
+		}
+		if image.FileSize == 0 {
+			problem(fmt.Sprintf("attached_images[%d].file_size", i), ProblemMissing, nil)
+		}
+	}
+
+	if album.Video != nil {
+		if album.Video.DownloadLink == "" {
+			problem("attached_video.high", ProblemMissing, nil)
+		}
+		if album.Video.FileSize == 0 {
+			problem("attached_video.file_size", ProblemMissing, nil)
+		}
+	}
+
+	return problems
+}
+
+// unknownFields returns the paths of the fields in raw that the type t doesn't have
+func unknownFields(raw json.RawMessage, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil
+		}
+
+		known := jsonFields(t)
+		for name, value := range fields {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+
+			fieldType, ok := known[name]
+			if !ok {
+				unknown = append(unknown, fieldPath)
+				continue
+			}
+			unknown = append(unknown, unknownFields(value, fieldType, fieldPath)...)
+		}
+	case reflect.Slice:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil
+		}
+
+		seen := make(map[string]bool)
+		for _, item := range items {
+			for _, field := range unknownFields(item, t.Elem(), path+"[]") {
+				if !seen[field] {
+					seen[field] = true
+					unknown = append(unknown, field)
+				}
+			}
+		}
+	}
+
+	sort.Strings(unknown)
+	return unknown
+}
+
+// jsonFields returns the types of the struct's fields by their JSON names
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
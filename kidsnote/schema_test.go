@@ -0,0 +1,105 @@
+package kidsnote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAlbumsSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		album      string
+		strict     bool
+		wantErr    bool
+		wantAlbums int
+		wantReason []string
+	}{
+		{
+			name:       "known fields",
+			album:      `{"id": 1, "created": "2024-05-01T12:00:00.000000Z", "attached_images": [{"id": 2, "file_size": 10, "original": "https://img/2.jpg"}]}`,
+			strict:     true,
+			wantAlbums: 1,
+		},
+		{
+			name:       "unknown fields pass strict mode",
+			album:      `{"id": 1, "created": "2024-05-01T12:00:00.000000Z", "modified": "x", "attached_images": [{"id": 2, "file_size": 10, "original": "https://img/2.jpg", "thumbnail": "https://img/2s.jpg"}]}`,
+			strict:     true,
+			wantAlbums: 1,
+			wantReason: []string{ProblemUnknown, ProblemUnknown},
+		},
+		{
+			name:       "missing download link",
+			album:      `{"id": 1, "created": "2024-05-01T12:00:00.000000Z", "attached_images": [{"id": 2, "file_size": 10}]}`,
+			wantAlbums: 1,
+			wantReason: []string{ProblemMissing},
+		},
+		{
+			name:       "missing download link in strict mode",
+			album:      `{"id": 1, "created": "2024-05-01T12:00:00.000000Z", "attached_images": [{"id": 2, "file_size": 10}]}`,
+			strict:     true,
+			wantErr:    true,
+			wantReason: []string{ProblemMissing},
+		},
+		{
+			name:       "undecodable album is dropped",
+			album:      `{"id": 1, "created": 42}`,
+			wantAlbums: 0,
+			wantReason: []string{ProblemInvalid},
+		},
+		{
+			name:       "undecodable album in strict mode",
+			album:      `{"id": 1, "created": 42}`,
+			strict:     true,
+			wantErr:    true,
+			wantReason: []string{ProblemInvalid},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"count": 1, "next": null, "previous": null, "results": [` + tt.album + `]}`))
+			}))
+			defer srv.Close()
+
+			var reasons []string
+			client, err := NewClient(Config{
+				AlbumURL:        srv.URL + "/children/%d/albums",
+				Strict:          tt.strict,
+				OnDecodeProblem: func(problem DecodeProblem) { reasons = append(reasons, problem.Reason) },
+			})
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+
+			page, err := client.Albums(context.Background(), 1, nil)
+			var schemaErr *SchemaError
+			if tt.wantErr != errors.As(err, &schemaErr) {
+				t.Fatalf("got error %v, want a SchemaError: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Albums: %v", err)
+				}
+				if len(page.Results) != tt.wantAlbums {
+					t.Errorf("decoded %d albums, want %d", len(page.Results), tt.wantAlbums)
+				}
+				if page.PageSize != 1 {
+					t.Errorf("page size %d, want 1", page.PageSize)
+				}
+			}
+
+			if len(reasons) != len(tt.wantReason) {
+				t.Fatalf("got problems %q, want %q", reasons, tt.wantReason)
+			}
+			for i := range reasons {
+				if reasons[i] != tt.wantReason[i] {
+					t.Errorf("got problems %q, want %q", reasons, tt.wantReason)
+				}
+			}
+		})
+	}
+}
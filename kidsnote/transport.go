@@ -0,0 +1,136 @@
+package kidsnote
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TransportConfig tunes the connections made by the transport NewTransport creates. Zero values keep Go's defaults.
+type TransportConfig struct {
+	// ConnectTimeout limits establishing the TCP connection
+	ConnectTimeout time.Duration
+	// TLSHandshakeTimeout limits the TLS handshake
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout limits waiting for the response headers once the request is sent
+	ResponseHeaderTimeout time.Duration
+	// ReadTimeout limits how long a connection may go without receiving any data, so a stalled download fails
+	// instead of hanging, while a slow but progressing one carries on
+	ReadTimeout time.Duration
+	// IdleConnTimeout is how long an unused keep-alive connection stays open
+	IdleConnTimeout time.Duration
+	// MaxIdleConnsPerHost is the number of keep-alive connections kept per host
+	MaxIdleConnsPerHost int
+	// ProxyURL is an http, https or socks5 proxy, the HTTP_PROXY and HTTPS_PROXY environment variables are used if empty
+	ProxyURL string
+	// CAFile is a PEM bundle of certificates trusted in addition to the system ones, e.g. for an intercepting proxy
+	CAFile string
+}
+
+// NewTransport creates an HTTP transport from the given configuration
+func NewTransport(cfg TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if cfg.ConnectTimeout > 0 {
+		dialer.Timeout = cfg.ConnectTimeout
+	}
+	transport.DialContext = dialer.DialContext
+	if cfg.ReadTimeout > 0 {
+		transport.DialContext = (&readTimeoutDialer{dialer: dialer, timeout: cfg.ReadTimeout}).DialContext
+	}
+
+	if cfg.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	}
+	if cfg.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+		if transport.MaxIdleConns < cfg.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = cfg.MaxIdleConnsPerHost
+		}
+	}
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", cfg.ProxyURL, err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CAFile != "" {
+		pool, err := certPool(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return transport, nil
+}
+
+// certPool returns the system certificates along with the ones in caFile
+func certPool(caFile string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in CA file %s", caFile)
+	}
+
+	return pool, nil
+}
+
+// readTimeoutDialer dials connections that fail a read after timeout without any data
+type readTimeoutDialer struct {
+	dialer  *net.Dialer
+	timeout time.Duration
+}
+
+func (d *readTimeoutDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &readTimeoutConn{Conn: conn, timeout: d.timeout}, nil
+}
+
+// readTimeoutConn extends the read deadline before every read. Idle keep-alive connections time out as well,
+// the transport then simply dials a new one.
+type readTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *readTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
@@ -0,0 +1,91 @@
+package listing
+
+import (
+	"context"
+
+	"github.com/karolistamutis/kidsnoter/models"
+)
+
+// AlbumCursor pulls albums from a listing, as returned by Lister.Albums:
+//
+//	albums := lister.Albums(ctx, childID, opts)
+//	defer albums.Close()
+//	for albums.Next() {
+//		album := albums.Album()
+//		...
+//	}
+//	if err := albums.Err(); err != nil {
+//		...
+//	}
+//
+// Listing hands over one album at a time, but may fetch up to the lister's page concurrency of pages ahead of
+// the album being read. It stops along with its requests when the cursor is closed.
+type AlbumCursor struct {
+	albums <-chan *models.Album
+	cancel context.CancelFunc
+	// done is closed once listing has returned and err is set
+	done  chan struct{}
+	album *models.Album
+	err   error
+}
+
+// Albums returns a cursor over the child's albums, or those of all children if childID is 0, newest first
+func (l *lister) Albums(ctx context.Context, childID int, opts *ListOptions) *AlbumCursor {
+	ctx, cancel := context.WithCancel(ctx)
+	albums := make(chan *models.Album)
+	c := &AlbumCursor{albums: albums, cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(c.done)
+		defer close(albums)
+		c.err = l.ListAlbums(ctx, childID, opts, albums)
+	}()
+
+	return c
+}
+
+// Next advances to the next album, returning false once there are no more or listing failed
+func (c *AlbumCursor) Next() bool {
+	album, ok := <-c.albums
+	if !ok {
+		<-c.done
+		c.album = nil
+		return false
+	}
+
+	c.album = album
+	return true
+}
+
+// Album returns the album Next advanced to
+func (c *AlbumCursor) Album() *models.Album {
+	return c.album
+}
+
+// Err returns the error listing ended with, once Next returned false. The failures of single pages
+// or children are ChildListingErrors joined with errors.Join.
+func (c *AlbumCursor) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close stops listing, cancelling requests in flight, and waits for it to return. Stopping early isn't
+// an error, so Err returns nil afterwards unless listing had already failed. Close can be called more than once.
+func (c *AlbumCursor) Close() {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	c.cancel()
+	for range c.albums {
+		// Drain the album the listing may be about to send
+	}
+	<-c.done
+	c.err = nil
+}
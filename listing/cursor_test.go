@@ -0,0 +1,65 @@
+package listing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAlbumCursor(t *testing.T) {
+	server := &albumServer{total: 12, pageSize: 5, failing: map[int]bool{3: true}}
+	l := newTestLister(t, server, 2)
+
+	albums := l.Albums(context.Background(), 1, nil)
+	defer albums.Close()
+
+	n := 0
+	for albums.Next() {
+		if albums.Album() == nil {
+			t.Fatal("Album returned nil after Next returned true")
+		}
+		n++
+	}
+	if n != 10 {
+		t.Errorf("cursor returned %d albums, want 10", n)
+	}
+
+	var listingErr *ChildListingError
+	if err := albums.Err(); !errors.As(err, &listingErr) || listingErr.Page != "3" {
+		t.Errorf("Err returned %v, want the failure of page 3", err)
+	}
+	if albums.Next() {
+		t.Error("Next returned true after the listing ended")
+	}
+}
+
+func TestAlbumCursorClose(t *testing.T) {
+	server := &albumServer{total: 500, pageSize: 5}
+	l := newTestLister(t, server, 4)
+
+	albums := l.Albums(context.Background(), 1, nil)
+	if !albums.Next() {
+		t.Fatalf("Next returned false, error: %v", albums.Err())
+	}
+	albums.Close()
+
+	if err := albums.Err(); err != nil {
+		t.Errorf("Err returned %v after Close, want nil", err)
+	}
+	if albums.Next() {
+		t.Error("Next returned true after Close")
+	}
+
+	requested := len(server.pages())
+	time.Sleep(50 * time.Millisecond)
+	if n := len(server.pages()); n != requested {
+		t.Errorf("%d pages requested after Close", n-requested)
+	}
+	// The first page plus at most pageConcurrency fetched ahead
+	if requested > 1+4 {
+		t.Errorf("requested %d pages for one album, want at most %d", requested, 1+4)
+	}
+
+	albums.Close()
+}
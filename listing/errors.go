@@ -0,0 +1,34 @@
+package listing
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ChildListingError is the failure of a page, or of an album fetched by ID, while listing a child's albums.
+// ListAlbums joins these with errors.Join, so errors.As finds the child and errors.Is the cause, e.g. kidsnote.ErrUnauthorized.
+type ChildListingError struct {
+	ChildID int
+	// Page is the cursor of the page that failed, empty for the first page
+	Page string
+	// AlbumID is the album that failed when albums are fetched by ID
+	AlbumID int
+	// Listed is the number of albums of the child sent before the failure
+	Listed int
+	Err    error
+}
+
+func (e *ChildListingError) Error() string {
+	at := "the first page"
+	switch {
+	case e.AlbumID != 0:
+		at = "album ID " + strconv.Itoa(e.AlbumID)
+	case e.Page != "":
+		at = "page " + strconv.Quote(e.Page)
+	}
+	return fmt.Sprintf("failed to list albums of child ID %d at %s after %d albums: %v", e.ChildID, at, e.Listed, e.Err)
+}
+
+func (e *ChildListingError) Unwrap() error {
+	return e.Err
+}
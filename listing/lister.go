@@ -2,107 +2,53 @@ package listing
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/karolistamutis/kidsnoter/config"
+	"github.com/karolistamutis/kidsnoter/kidsnote"
 	"github.com/karolistamutis/kidsnoter/logger"
 	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/retry"
 	"github.com/karolistamutis/kidsnoter/util"
-	"github.com/valyala/fastjson"
-	"io"
-	"net/http"
-	"net/url"
-	"strings"
+	"strconv"
 	"sync"
 )
 
 type Lister interface {
 	ListChildren(ctx context.Context) ([]*models.Child, error)
-	ListAlbums(ctx context.Context, childID int, albumChan chan<- *models.Album) error
+	ListAlbums(ctx context.Context, childID int, opts *ListOptions, albumChan chan<- *models.Album) error
+	Albums(ctx context.Context, childID int, opts *ListOptions) *AlbumCursor
+	ListReports(ctx context.Context, childID int, opts *ListOptions, reportChan chan<- *models.Report) error
+	ListNotices(ctx context.Context, board models.NoticeBoard, opts *ListOptions, noticeChan chan<- *models.Notice) error
 	Children() ([]*models.Child, error)
 }
 
 type lister struct {
-	client   *http.Client
-	children []*models.Child
+	client          *kidsnote.Client
+	retry           retry.Policy
+	pageConcurrency int
+	children        []*models.Child
 }
 
-func NewLister(client *http.Client) Lister {
-	return &lister{client: client}
+// NewLister creates a Lister retrying failed API calls according to the given policy and fetching up to
+// pageConcurrency album pages of a child at once
+func NewLister(client *kidsnote.Client, retryPolicy retry.Policy, pageConcurrency int) Lister {
+	return &lister{client: client, retry: retryPolicy, pageConcurrency: pageConcurrency}
 }
 
 func (l *lister) ListChildren(ctx context.Context) ([]*models.Child, error) {
 	var children []*models.Child
 
-	err := util.RetryWithBackoff(ctx, func() error {
-		apiInfoURL := config.GetAPIInfoURL()
-		req, err := http.NewRequestWithContext(ctx, "GET", apiInfoURL, nil)
-		if err != nil {
-			return fmt.Errorf("failed to create request for URL %s: %v", apiInfoURL, err)
-		}
-
-		resp, err := l.client.Do(req)
-		if err != nil {
-			return fmt.Errorf("failed to execute request for URL %s: %v", apiInfoURL, err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("received non-OK HTTP status %s from URL %s", resp.Status, apiInfoURL)
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read response body from URL %s: %v", apiInfoURL, err)
-		}
-
-		var p fastjson.Parser
-		value, err := p.ParseBytes(body)
-		if err != nil {
-			return fmt.Errorf("failed to parse JSON data: %v", err)
-		}
-
-		// Extract the children data
-		childrenArray := value.GetArray("children")
-		if childrenArray == nil {
-			return fmt.Errorf("no children data found in JSON from URL %s", apiInfoURL)
-		}
-
-		children = make([]*models.Child, 0, len(childrenArray))
-		for _, child := range childrenArray {
-			id := child.GetInt("id")
-			name := string(child.GetStringBytes("name"))
-			gender := string(child.GetStringBytes("gender"))
-			dateOfBirth := string(child.GetStringBytes("date_birth"))
-
-			enrollment := child.GetArray("enrollment")
-			if len(enrollment) == 0 {
-				return fmt.Errorf("enrollment details missing for child ID %d", id)
-			}
-
-			centerID := enrollment[0].GetInt("center_id")
-			classID := enrollment[0].GetInt("belong_to_class")
-
-			if centerID == 0 || classID == 0 {
-				return fmt.Errorf("invalid center or class IDs for child %s [ID: %d]", name, id)
-			}
-
-			children = append(children, &models.Child{
-				ID:          id,
-				CenterID:    centerID,
-				ClassID:     classID,
-				Name:        name,
-				Gender:      gender,
-				DateOfBirth: dateOfBirth,
-			})
-		}
-		l.children = children
-		return nil
+	err := l.retry.Do(ctx, func() error {
+		var err error
+		children, err = l.client.Children(ctx)
+		return err
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	l.children = children
 	return l.children, nil
 }
 
@@ -113,212 +59,440 @@ func (l *lister) Children() ([]*models.Child, error) {
 	return l.children, nil
 }
 
-func (l *lister) ListAlbums(ctx context.Context, childID int, albumChan chan<- *models.Album) error {
-	// If childID is 0, list albums for all children in l.children slice.
+// ListAlbums sends the child's albums, or those of all children if childID is 0, to albumChan, newest first.
+// Listing goes on past failed pages and children where it can. The failures are returned as ChildListingErrors
+// joined with errors.Join.
+func (l *lister) ListAlbums(ctx context.Context, childID int, opts *ListOptions, albumChan chan<- *models.Album) error {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+
+	return l.forChildren(childID, func(childID int) error {
+		return l.listAlbumsForChild(ctx, childID, opts, albumChan)
+	})
+}
+
+// ListReports sends the child's daily reports to reportChan, newest first. Only the time bounds of opts apply.
+func (l *lister) ListReports(ctx context.Context, childID int, opts *ListOptions, reportChan chan<- *models.Report) error {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+
+	return l.forChildren(childID, func(childID int) error {
+		return l.listReportsForChild(ctx, childID, opts, reportChan)
+	})
+}
+
+// forChildren runs list for the given child, or concurrently for all children if childID is 0,
+// joining the errors of all children
+func (l *lister) forChildren(childID int, list func(childID int) error) error {
 	var wg sync.WaitGroup
 	var errs []error  // Slice to collect errors
 	var mu sync.Mutex // Protects access to errs
 
-	// Handle case where specific child ID is provided
-	if childID != 0 {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if err := l.listAlbumsForChild(ctx, childID, albumChan); err != nil {
-				mu.Lock()
-				errs = append(errs, err)
-				mu.Unlock()
-			}
-		}()
-	} else {
+	childIDs := []int{childID}
+	if childID == 0 {
 		// Ensure children data is populated
-		if l.children == nil || len(l.children) == 0 {
+		if len(l.children) == 0 {
 			return fmt.Errorf("children data has not been populated, check child ID or name first")
 		}
 
-		// Iterate over all children
+		childIDs = childIDs[:0]
 		for _, child := range l.children {
-			child := child // Capture range variable
-
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				if err := l.listAlbumsForChild(ctx, child.ID, albumChan); err != nil {
-					mu.Lock()
-					errs = append(errs, err)
-					mu.Unlock()
-				}
-			}()
+			childIDs = append(childIDs, child.ID)
 		}
 	}
 
-	wg.Wait()
-
-	if len(errs) > 0 {
-		return fmt.Errorf("encountered errors during album listing: %v", errs)
+	for _, id := range childIDs {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if err := list(id); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(id)
 	}
 
-	return nil
+	wg.Wait()
+
+	return errors.Join(errs...)
 }
 
-func (l *lister) listAlbumsForChild(ctx context.Context, childID int, albumChan chan<- *models.Album) error {
-	// Find the child name from l.children.
-	var childName string
+// child finds a child listed by ListChildren
+func (l *lister) child(childID int) (*models.Child, error) {
 	for _, child := range l.children {
 		if child.ID == childID {
-			childName = child.Name
-			break
+			return child, nil
 		}
 	}
+	return nil, fmt.Errorf("child with ID %d not found", childID)
+}
+
+// albumListing is the state of listing a child's albums
+type albumListing struct {
+	ctx       context.Context
+	child     *models.Child
+	opts      *ListOptions
+	albumChan chan<- *models.Album
+	// seen holds the albums already sent, so ones moved to the next page by a newly posted album are skipped.
+	// It's nil for albums fetched by ID.
+	seen map[int]bool
+	// listed counts the albums sent and errs collects the pages or albums that failed
+	listed int
+	errs   []error
+}
+
+// fail records a failure, filling in the child and how far listing got
+func (a *albumListing) fail(err *ChildListingError) {
+	err.ChildID = a.child.ID
+	err.Listed = a.listed
+	a.errs = append(a.errs, err)
+}
+
+func (l *lister) listAlbumsForChild(ctx context.Context, childID int, opts *ListOptions, albumChan chan<- *models.Album) error {
+	child, err := l.child(childID)
+	if err != nil {
+		return err
+	}
+
+	a := &albumListing{ctx: ctx, child: child, opts: opts, albumChan: albumChan}
+	if len(opts.AlbumIDs) > 0 {
+		l.fetchAlbumsForChild(ctx, a)
+	} else {
+		a.seen = make(map[int]bool)
+		l.pageAlbumsForChild(ctx, a)
+	}
+
+	if len(a.errs) > 0 {
+		logger.Log.Warnf("listed %d albums for child ID %d with %d failures", a.listed, childID, len(a.errs))
+		return errors.Join(a.errs...)
+	}
+	// Listing stopped while sending an album
+	return ctx.Err()
+}
+
+// pageAlbumsForChild pages through the child's albums, newest first
+func (l *lister) pageAlbumsForChild(ctx context.Context, a *albumListing) {
+	first, err := l.albumsPage(ctx, a.child.ID, "")
+	if err != nil {
+		a.fail(&ChildListingError{Err: err})
+		return
+	}
 
-	if childName == "" {
-		return fmt.Errorf("child with ID %d not found", childID)
+	if done := l.streamAlbums(a, first.Results); done {
+		logger.Log.Debugf("reached albums older than requested for child ID %d, stopping", a.child.ID)
+		return
 	}
 
-	next := fmt.Sprintf(config.GetAPIAlbumURL(), childID)
+	next := first.Next
+
+	// With page numbers for cursors, the album count tells which pages follow, so they can be fetched concurrently
+	if firstPage, err := strconv.Atoi(next); err == nil && first.PageSize > 0 && l.pageConcurrency > 1 {
+		lastPage := (first.Count + first.PageSize - 1) / first.PageSize
+		if lastPage >= firstPage {
+			var done bool
+			next, done = l.fetchPages(ctx, a, firstPage, lastPage)
+			if done {
+				logger.Log.Debugf("reached albums older than requested for child ID %d, stopping", a.child.ID)
+				return
+			}
+		}
+	}
+
+	// Pages added since the count was taken, or all pages if cursors aren't page numbers, are fetched one by one.
+	// A failed page ends listing here, as the cursor of the following page comes with it.
 	for next != "" {
-		body, err := l.albumData(ctx, next)
+		page, err := l.albumsPage(ctx, a.child.ID, next)
 		if err != nil {
-			return fmt.Errorf("failed to make request for URL %s: %v", next, err)
+			a.fail(&ChildListingError{Page: next, Err: err})
+			return
 		}
 
-		p := fastjson.Parser{}
-		value, err := p.ParseBytes(body)
-		if err != nil {
-			return fmt.Errorf("failed to parse JSON data from URL %s: %v", next, err)
+		if done := l.streamAlbums(a, page.Results); done {
+			logger.Log.Debugf("reached albums older than requested for child ID %d, stopping", a.child.ID)
+			return
 		}
+		next = page.Next
+	}
+}
 
-		next, err = l.nexAlbumsURL(next, value)
-		if err != nil {
-			return fmt.Errorf("failed to extract next URL for URL %s: %v", next, err)
+// fetchPages fetches the numbered pages from first to last with up to pageConcurrency requests in flight,
+// streaming their albums in page order. A failed page is skipped unless it stops listing altogether.
+// It returns the cursor following the last page, empty if that page failed, and whether listing is done.
+func (l *lister) fetchPages(ctx context.Context, a *albumListing, first, last int) (string, bool) {
+	// Stops the requests still in flight once streaming is done
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type pageResult struct {
+		page *models.AlbumPage
+		err  error
+	}
+
+	results := make([]chan pageResult, last-first+1)
+	for i := range results {
+		results[i] = make(chan pageResult, 1)
+	}
+
+	// A slot is taken for every page being fetched or waiting to be streamed, so fetching stays
+	// at most pageConcurrency pages ahead of streaming
+	window := make(chan struct{}, l.pageConcurrency)
+	go func() {
+		for i := range results {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(i int) {
+				page, err := l.albumsPage(ctx, a.child.ID, strconv.Itoa(first+i))
+				results[i] <- pageResult{page: page, err: err}
+			}(i)
 		}
+	}()
+
+	next := ""
+	for i, result := range results {
+		cursor := strconv.Itoa(first + i)
+
+		var r pageResult
+		select {
+		case r = <-result:
+		case <-ctx.Done():
+			a.fail(&ChildListingError{Page: cursor, Err: ctx.Err()})
+			return "", false
+		}
+		<-window
 
-		err = l.streamPageAlbums(childName, value, albumChan)
-		if err != nil {
-			return fmt.Errorf("failed to get page albums from URL %s: %v", next, err)
+		// Albums deleted since the count was taken leave fewer pages than expected, the missing ones end the listing
+		if errors.Is(r.err, kidsnote.ErrNotFound) {
+			logger.Log.Debugf("albums page %s not found for child ID %d, listing ends there", cursor, a.child.ID)
+			return "", false
 		}
+		if r.err != nil {
+			a.fail(&ChildListingError{Page: cursor, Err: r.err})
+			if stopsListing(ctx, r.err) {
+				return "", false
+			}
+			next = ""
+			continue
+		}
+		if done := l.streamAlbums(a, r.page.Results); done {
+			return "", true
+		}
+		next = r.page.Next
 	}
-	return nil
+
+	return next, false
 }
 
-func (l *lister) albumData(ctx context.Context, albumsURL string) ([]byte, error) {
-	var body []byte
-	var respErr error
+// stopsListing tells whether a failure leaves no point in going on with the remaining pages or albums
+func stopsListing(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, kidsnote.ErrUnauthorized)
+}
+
+// albumsPage fetches a page of the child's albums, retrying according to the lister's policy
+func (l *lister) albumsPage(ctx context.Context, childID int, cursor string) (*models.AlbumPage, error) {
+	var page *models.AlbumPage
+	err := l.retry.Do(ctx, func() error {
+		var err error
+		page, err = l.client.Albums(ctx, childID, &kidsnote.PageOptions{Page: cursor})
+		return err
+	})
+	return page, err
+}
 
-	err := util.RetryWithBackoff(ctx, func() error {
-		req, err := http.NewRequestWithContext(ctx, "GET", albumsURL, nil)
+// fetchAlbumsForChild gets the albums selected by ID one by one instead of paging through all of them
+func (l *lister) fetchAlbumsForChild(ctx context.Context, a *albumListing) {
+	for _, albumID := range a.opts.AlbumIDs {
+		var album *models.Album
+		err := l.retry.Do(ctx, func() error {
+			var err error
+			album, err = l.client.Album(ctx, a.child.ID, albumID)
+			return err
+		})
+		if errors.Is(err, kidsnote.ErrNotFound) {
+			logger.Log.Debugf("album ID %d not found for child ID %d", albumID, a.child.ID)
+			continue
+		}
 		if err != nil {
-			return fmt.Errorf("failed to create request for URL %s: %v", albumsURL, err)
+			a.fail(&ChildListingError{AlbumID: albumID, Err: err})
+			if stopsListing(ctx, err) {
+				return
+			}
+			continue
 		}
 
-		resp, err := l.client.Do(req)
-		if err != nil {
-			return fmt.Errorf("failed to execute request for URL %s: %v", albumsURL, err)
+		l.streamAlbums(a, []*models.Album{album})
+	}
+}
+
+// streamAlbums sends the albums selected by the options to the album channel and reports whether listing is done,
+// either because the albums got older than requested or because the context was cancelled
+func (l *lister) streamAlbums(a *albumListing, albums []*models.Album) bool {
+	for _, album := range albums {
+		if a.seen != nil {
+			if a.seen[album.ID] {
+				continue
+			}
+			a.seen[album.ID] = true
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("received non-OK HTTP status %s from URL %s", resp.Status, albumsURL)
+		selected, done := a.opts.check(album)
+		if done {
+			return true
+		}
+		if !selected {
+			continue
 		}
 
-		body, respErr = io.ReadAll(resp.Body)
-		return respErr
-	})
+		logger.Log.Debugf("got %d images for album \"%s\"", len(album.Images), album.Title)
 
-	if err != nil {
-		return nil, err
+		generatedFolderName, err := util.GenerateFolderName(a.child.Name, album.Date, album.ID, album.Title)
+		if err != nil {
+			logger.Log.Warnf("failed to generate folder name for album ID %d: %v, skipping", album.ID, err)
+			continue
+		}
+		logger.Log.Debugf("generated folder name: %s for album title %s", generatedFolderName, album.Title)
+
+		album.GeneratedFolderName = generatedFolderName
+		if created, err := util.ParseAlbumDate(album.Date); err == nil {
+			album.Enrollment = a.child.EnrollmentAt(created)
+		}
+		select {
+		case a.albumChan <- album:
+			a.listed++
+		case <-a.ctx.Done():
+			return true
+		}
 	}
-	return body, nil
+	return false
 }
 
-func (l *lister) nexAlbumsURL(baseURL string, value *fastjson.Value) (string, error) {
-	next := value.GetStringBytes("next")
-	if next == nil {
-		return "", nil
-	}
-	u, err := url.Parse(baseURL)
+func (l *lister) listReportsForChild(ctx context.Context, childID int, opts *ListOptions, reportChan chan<- *models.Report) error {
+	child, err := l.child(childID)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse URL %s: %v", baseURL, err)
+		return err
 	}
 
-	q := u.Query()
-	q.Set("page", string(next))
-	u.RawQuery = q.Encode()
+	pageOpts := &kidsnote.PageOptions{}
+	for {
+		var page *models.ReportPage
+		err := l.retry.Do(ctx, func() error {
+			var err error
+			page, err = l.client.Reports(ctx, childID, pageOpts)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get reports page %q for child ID %d: %w", pageOpts.Page, childID, err)
+		}
 
-	return u.String(), nil
-}
+		if done := l.streamReports(ctx, child.Name, page.Results, opts, reportChan); done {
+			logger.Log.Debugf("reached reports older than requested for child ID %d, stopping", childID)
+			return ctx.Err()
+		}
 
-func (l *lister) streamPageAlbums(childName string, value *fastjson.Value, albumChan chan<- *models.Album) error {
-	albumArray := value.GetArray("results")
-	if albumArray == nil {
-		return fmt.Errorf("no album data found")
+		if page.Next == "" {
+			return nil
+		}
+		pageOpts.Page = page.Next
 	}
+}
 
-	for _, album := range albumArray {
-		id := album.GetInt("id")
-		date := string(album.GetStringBytes("created"))
-		title := string(album.GetStringBytes("title"))
-		content := string(album.GetStringBytes("content"))
-		video := l.getAlbumVideo(album.GetObject("attached_video"))
-		images := l.getAlbumImages(album.GetArray("attached_images"))
-
-		logger.Log.Debugf("got %d images for album \"%s\"", len(images), title)
+// streamReports sends the reports selected by opts to reportChan and reports whether listing is done,
+// including when the context was cancelled
+func (l *lister) streamReports(ctx context.Context, childName string, reports []*models.Report, opts *ListOptions, reportChan chan<- *models.Report) bool {
+	for _, report := range reports {
+		selected, done := opts.checkReport(report)
+		if done {
+			return true
+		}
+		if !selected {
+			continue
+		}
 
-		generatedFolderName, err := util.GenerateFolderName(childName, date, id, title)
+		generatedFolderName, err := util.GenerateReportFolderName(childName, report.Date, report.ID)
 		if err != nil {
-			logger.Log.Warnf("failed to generate folder name for album ID %d: %v, skipping", id, err)
+			logger.Log.Warnf("failed to generate folder name for report ID %d: %v, skipping", report.ID, err)
 			continue
 		}
-		logger.Log.Debugf("generated folder name: %s for album title %s", generatedFolderName, title)
 
-		albumChan <- &models.Album{
-			ID:                  id,
-			GeneratedFolderName: generatedFolderName,
-			Date:                date,
-			Title:               title,
-			Content:             content,
-			Video:               video,
-			Images:              images,
+		report.GeneratedFolderName = generatedFolderName
+		select {
+		case reportChan <- report:
+		case <-ctx.Done():
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
-func (l *lister) getAlbumVideo(videoObject *fastjson.Object) *models.Video {
-	if videoObject == nil {
-		return nil
-	}
-	id, err := videoObject.Get("id").Int()
-	if err != nil {
-		id = 0
+// ListNotices sends the notices of a center or class to noticeChan, newest first. Only the time bounds of opts apply.
+func (l *lister) ListNotices(ctx context.Context, board models.NoticeBoard, opts *ListOptions, noticeChan chan<- *models.Notice) error {
+	if opts == nil {
+		opts = &ListOptions{}
 	}
-	fileName := strings.Trim(videoObject.Get("original_file_name").String(), "\"")
-	fileSize, err := videoObject.Get("file_size").Int()
+
+	boardFolder, err := util.GenerateNoticeBoardFolderName(board.CenterName, board.CenterID, board.ClassName, board.ClassID)
 	if err != nil {
-		fileSize = 0
+		return err
 	}
-	downloadLink := strings.Trim(videoObject.Get("high").String(), "\"")
 
-	return &models.Video{
-		ID:           id,
-		FileName:     fileName,
-		FileSize:     fileSize,
-		DownloadLink: downloadLink,
+	pageOpts := &kidsnote.PageOptions{}
+	for {
+		var page *models.NoticePage
+		err := l.retry.Do(ctx, func() error {
+			var err error
+			if board.ClassID != 0 {
+				page, err = l.client.ClassNotices(ctx, board.ClassID, pageOpts)
+			} else {
+				page, err = l.client.CenterNotices(ctx, board.CenterID, pageOpts)
+			}
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get notices page %q of %s: %w", pageOpts.Page, boardFolder, err)
+		}
+
+		if done := l.streamNotices(ctx, boardFolder, page.Results, opts, noticeChan); done {
+			logger.Log.Debugf("reached notices older than requested in %s, stopping", boardFolder)
+			return ctx.Err()
+		}
+
+		if page.Next == "" {
+			return nil
+		}
+		pageOpts.Page = page.Next
 	}
 }
 
-func (l *lister) getAlbumImages(imageArray []*fastjson.Value) []*models.Image {
-	if imageArray == nil {
-		return nil
-	}
+// streamNotices sends the notices selected by opts to noticeChan and reports whether listing is done,
+// including when the context was cancelled
+func (l *lister) streamNotices(ctx context.Context, boardFolder string, notices []*models.Notice, opts *ListOptions, noticeChan chan<- *models.Notice) bool {
+	for _, notice := range notices {
+		selected, done := opts.checkNotice(notice)
+		if done {
+			return true
+		}
+		if !selected {
+			continue
+		}
 
-	var images []*models.Image
-	for _, image := range imageArray {
-		images = append(images, &models.Image{
-			ID:           image.GetInt("id"),
-			FileSize:     image.GetInt("file_size"),
-			DownloadLink: string(image.GetStringBytes("original")),
-		})
+		generatedFolderName, err := util.GenerateNoticeFolderName(boardFolder, notice.Created, notice.ID, notice.Title)
+		if err != nil {
+			logger.Log.Warnf("failed to generate folder name for notice ID %d: %v, skipping", notice.ID, err)
+			continue
+		}
+
+		notice.GeneratedFolderName = generatedFolderName
+		select {
+		case noticeChan <- notice:
+		case <-ctx.Done():
+			return true
+		}
 	}
-	return images
+	return false
 }
@@ -0,0 +1,303 @@
+package listing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/retry"
+)
+
+// albumServer serves a child's albums in pages like the albums endpoint, newest first with IDs counting down from total
+type albumServer struct {
+	total    int
+	pageSize int
+	// count overrides the album count the pages report, e.g. one taken before albums were deleted
+	count int
+	// undecodable albums are served with a malformed created date, so the client drops them
+	undecodable map[int]bool
+	// failing pages are answered with a server error
+	failing map[int]bool
+
+	mu       sync.Mutex
+	requests []int
+}
+
+func (s *albumServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		page, _ = strconv.Atoi(p)
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, page)
+	s.mu.Unlock()
+
+	if s.failing[page] {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	start := (page - 1) * s.pageSize
+	if page < 1 || start >= s.total {
+		http.NotFound(w, r)
+		return
+	}
+	end := min(start+s.pageSize, s.total)
+
+	results := make([]map[string]any, 0, end-start)
+	for i := start; i < end; i++ {
+		id := s.total - i
+		created := any(albumDate(i))
+		if s.undecodable[id] {
+			created = 42
+		}
+		results = append(results, map[string]any{"id": id, "title": "album " + strconv.Itoa(id), "created": created})
+	}
+
+	count := s.total
+	if s.count != 0 {
+		count = s.count
+	}
+	var next any
+	if end < s.total {
+		next = strconv.Itoa(page + 1)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"count": count, "next": next, "previous": nil, "results": results})
+}
+
+// pages returns the pages requested so far
+func (s *albumServer) pages() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.requests...)
+}
+
+// albumDate returns the creation date of the i-th newest album
+func albumDate(i int) string {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return base.Add(-time.Duration(i) * time.Hour).Format("2006-01-02T15:04:05.000000Z")
+}
+
+func newTestLister(t *testing.T, handler http.Handler, pageConcurrency int) *lister {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := kidsnote.NewClient(kidsnote.Config{AlbumURL: srv.URL + "/children/%d/albums"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	return &lister{
+		client:          client,
+		retry:           retry.Policy{Operation: "listing", MaxAttempts: 1},
+		pageConcurrency: pageConcurrency,
+		children:        []*models.Child{{ID: 1, Name: "Kid"}},
+	}
+}
+
+func TestListAlbumsPages(t *testing.T) {
+	tests := []struct {
+		name       string
+		server     *albumServer
+		wantAlbums int
+		// wantSequential is the number of albums listed when pages are fetched one by one, if it differs
+		wantSequential int
+		wantFailed     []string
+		wantMaxPage    int
+	}{
+		{
+			name:        "single page",
+			server:      &albumServer{total: 3, pageSize: 5},
+			wantAlbums:  3,
+			wantMaxPage: 1,
+		},
+		{
+			name:        "full pages",
+			server:      &albumServer{total: 20, pageSize: 5},
+			wantAlbums:  20,
+			wantMaxPage: 4,
+		},
+		{
+			name:        "partial last page",
+			server:      &albumServer{total: 22, pageSize: 5},
+			wantAlbums:  22,
+			wantMaxPage: 5,
+		},
+		{
+			name:        "dropped albums on the first page",
+			server:      &albumServer{total: 12, pageSize: 5, undecodable: map[int]bool{12: true, 10: true}},
+			wantAlbums:  10,
+			wantMaxPage: 3,
+		},
+		{
+			name:        "whole first page dropped",
+			server:      &albumServer{total: 10, pageSize: 5, undecodable: map[int]bool{10: true, 9: true, 8: true, 7: true, 6: true}},
+			wantAlbums:  5,
+			wantMaxPage: 2,
+		},
+		{
+			name:        "dropped albums on later pages",
+			server:      &albumServer{total: 15, pageSize: 5, undecodable: map[int]bool{3: true}},
+			wantAlbums:  14,
+			wantMaxPage: 3,
+		},
+		{
+			name:        "albums deleted since the count",
+			server:      &albumServer{total: 10, pageSize: 5, count: 18},
+			wantAlbums:  10,
+			wantMaxPage: 4,
+		},
+		{
+			// Fetched one by one, the failed page ends listing as the cursor of the next one comes with it
+			name:           "failed page",
+			server:         &albumServer{total: 20, pageSize: 5, failing: map[int]bool{2: true}},
+			wantAlbums:     15,
+			wantSequential: 5,
+			wantFailed:     []string{"2"},
+			wantMaxPage:    4,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, pageConcurrency := range []int{1, 3} {
+			t.Run(tt.name+"/concurrency "+strconv.Itoa(pageConcurrency), func(t *testing.T) {
+				tt.server.requests = nil
+				l := newTestLister(t, tt.server, pageConcurrency)
+
+				albumChan := make(chan *models.Album)
+				var albums []*models.Album
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					for album := range albumChan {
+						albums = append(albums, album)
+					}
+				}()
+
+				err := l.ListAlbums(context.Background(), 1, nil, albumChan)
+				close(albumChan)
+				<-done
+
+				wantAlbums := tt.wantAlbums
+				if pageConcurrency == 1 && tt.wantSequential != 0 {
+					wantAlbums = tt.wantSequential
+				}
+				if len(albums) != wantAlbums {
+					t.Errorf("listed %d albums, want %d", len(albums), wantAlbums)
+				}
+				for i := 1; i < len(albums); i++ {
+					if albums[i].ID >= albums[i-1].ID {
+						t.Errorf("album %d listed after album %d, want newest first", albums[i].ID, albums[i-1].ID)
+					}
+				}
+
+				var failed []string
+				for _, e := range unwrapJoined(err) {
+					var listingErr *ChildListingError
+					if !errors.As(e, &listingErr) {
+						t.Fatalf("error %v is not a ChildListingError", e)
+					}
+					failed = append(failed, listingErr.Page)
+				}
+				if !equalStrings(failed, tt.wantFailed) {
+					t.Errorf("failed pages %q, want %q (error: %v)", failed, tt.wantFailed, err)
+				}
+
+				maxPage := 0
+				for _, page := range tt.server.pages() {
+					maxPage = max(maxPage, page)
+				}
+				if maxPage > tt.wantMaxPage {
+					t.Errorf("requested page %d, want none past page %d", maxPage, tt.wantMaxPage)
+				}
+			})
+		}
+	}
+}
+
+func TestListAlbumsStopsAtAfter(t *testing.T) {
+	server := &albumServer{total: 50, pageSize: 5}
+	l := newTestLister(t, server, 4)
+
+	after, _ := time.Parse("2006-01-02T15:04:05.000000Z", albumDate(7))
+	albumChan := make(chan *models.Album, 50)
+	if err := l.ListAlbums(context.Background(), 1, &ListOptions{After: after}, albumChan); err != nil {
+		t.Fatalf("ListAlbums: %v", err)
+	}
+	close(albumChan)
+
+	if n := len(albumChan); n != 7 {
+		t.Errorf("listed %d albums, want the 7 created after the bound", n)
+	}
+	// Pages are fetched at most pageConcurrency ahead of the one that reached the bound
+	for _, page := range server.pages() {
+		if page > 2+4 {
+			t.Errorf("requested page %d, want none past page %d", page, 2+4)
+		}
+	}
+}
+
+func TestListAlbumsDateRange(t *testing.T) {
+	server := &albumServer{total: 50, pageSize: 5}
+	l := newTestLister(t, server, 1)
+
+	// Albums are an hour apart, the window holds the 10th to 20th newest
+	since, _ := time.Parse("2006-01-02T15:04:05.000000Z", albumDate(20))
+	until, _ := time.Parse("2006-01-02T15:04:05.000000Z", albumDate(9))
+	albumChan := make(chan *models.Album, 50)
+	if err := l.ListAlbums(context.Background(), 1, &ListOptions{Since: since, Until: until}, albumChan); err != nil {
+		t.Fatalf("ListAlbums: %v", err)
+	}
+	close(albumChan)
+
+	var ids []int
+	for album := range albumChan {
+		ids = append(ids, album.ID)
+	}
+	if len(ids) != 11 || ids[0] != 50-10 || ids[len(ids)-1] != 50-20 {
+		t.Errorf("listed albums %v, want 40 down to 30", ids)
+	}
+	// Listing stops on the page holding the first album created before since
+	if pages := server.pages(); pages[len(pages)-1] != 5 {
+		t.Errorf("requested pages %v, want none past page 5", pages)
+	}
+}
+
+// unwrapJoined returns the errors joined with errors.Join, or err itself if it wasn't joined
+func unwrapJoined(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, unwrapJoined(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package listing
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/karolistamutis/kidsnoter/models"
+	"github.com/karolistamutis/kidsnoter/util"
+)
+
+// ListOptions narrows down album, report and notice listing, nil options list everything. All set options have to match,
+// reports and notices are only selected by the time bounds. Everything comes newest first, so once listing passes
+// the lower bound the remaining pages are not fetched at all.
+type ListOptions struct {
+	// After ends listing at the first album not created after it, e.g. one synced by an earlier run
+	After time.Time
+	// Since ends listing at the first album created before it
+	Since time.Time
+	// Until skips albums created at or after it
+	Until time.Time
+	// AlbumIDs fetches just these albums directly instead of paging through all of them
+	AlbumIDs []int
+	// TitleMatch skips albums whose title doesn't match
+	TitleMatch *regexp.Regexp
+}
+
+// Filtered reports whether the options select a subset of the albums, apart from the ones synced earlier
+func (o *ListOptions) Filtered() bool {
+	return o != nil && (!o.Since.IsZero() || !o.Until.IsZero() || len(o.AlbumIDs) > 0 || o.TitleMatch != nil)
+}
+
+// check reports whether the album is selected and whether listing went past the lower bounds and can stop
+func (o *ListOptions) check(album *models.Album) (bool, bool) {
+	if selected, done := o.checkCreated(album.Date, "album", album.ID); !selected {
+		return false, done
+	}
+
+	if o.TitleMatch != nil && !o.TitleMatch.MatchString(album.Title) {
+		return false, false
+	}
+
+	return true, false
+}
+
+// checkReport is check for reports
+func (o *ListOptions) checkReport(report *models.Report) (bool, bool) {
+	return o.checkCreated(report.Created, "report", report.ID)
+}
+
+// checkNotice is check for notices
+func (o *ListOptions) checkNotice(notice *models.Notice) (bool, bool) {
+	return o.checkCreated(notice.Created, "notice", notice.ID)
+}
+
+// checkCreated applies the time bounds to the creation date of an album, report or notice
+func (o *ListOptions) checkCreated(date string, kind string, id int) (bool, bool) {
+	if o.After.IsZero() && o.Since.IsZero() && o.Until.IsZero() {
+		return true, false
+	}
+
+	created, err := util.ParseAlbumDate(date)
+	if err != nil {
+		logger.Log.Warnf("failed to parse creation date of %s ID %d: %v, listing it anyway", kind, id, err)
+		return true, false
+	}
+
+	if (!o.After.IsZero() && !created.After(o.After)) || (!o.Since.IsZero() && created.Before(o.Since)) {
+		return false, true
+	}
+	if !o.Until.IsZero() && !created.Before(o.Until) {
+		return false, false
+	}
+
+	return true, false
+}
@@ -0,0 +1,55 @@
+package listing
+
+import (
+	"sync"
+
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var decodeProblemsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "api_decode_problems_total",
+	Help: "The total number of API response fields that didn't match the expected schema, by item and reason",
+}, []string{"item", "reason"})
+
+// reportedFields holds the fields already warned about, so drift shows up once in the logs rather than once per album
+var reportedFields sync.Map
+
+// ReportDecodeProblem counts the problem and logs a warning the first time a field has it. Unknown fields are
+// only logged at debug level, as responses carry plenty of fields kidsnoter has no use for.
+func ReportDecodeProblem(problem kidsnote.DecodeProblem) {
+	if problem.Reason == kidsnote.ProblemUnknown {
+		logger.Log.Debugf("API response has a field kidsnoter doesn't know: %s", problem)
+		return
+	}
+	decodeProblemsTotal.WithLabelValues(problem.Item, problem.Reason).Inc()
+
+	key := problem.Item + "|" + problem.Reason + "|" + fieldPattern(problem.Field)
+	if _, reported := reportedFields.LoadOrStore(key, true); reported {
+		logger.Log.Debugf("API response doesn't match the expected schema: %s", problem)
+		return
+	}
+
+	logger.Log.Warnf("API response doesn't match the expected schema, the API may have changed: %s", problem)
+}
+
+// fieldPattern drops list indexes from a field path, e.g. attached_images[2].original becomes attached_images[].original
+func fieldPattern(field string) string {
+	pattern := make([]byte, 0, len(field))
+	inIndex := false
+	for i := 0; i < len(field); i++ {
+		switch c := field[i]; {
+		case c == '[':
+			inIndex = true
+			pattern = append(pattern, c)
+		case c == ']':
+			inIndex = false
+			pattern = append(pattern, c)
+		case !inIndex:
+			pattern = append(pattern, c)
+		}
+	}
+	return string(pattern)
+}
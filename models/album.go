@@ -1,9 +1,12 @@
 package models
 
 type AlbumPage struct {
-	count    int    `json:"count,omitempty"`
-	next     string `json:"next,omitempty"`
-	previous string `json:"previous,omitempty"`
+	Count    int      `json:"count,omitempty"`
+	Next     string   `json:"next,omitempty"`
+	Previous string   `json:"previous,omitempty"`
+	Results  []*Album `json:"results,omitempty"`
+	// PageSize is the number of albums the API sent on the page, Results leaves out those that couldn't be decoded
+	PageSize int `json:"-"`
 }
 
 type Album struct {
@@ -14,6 +17,29 @@ type Album struct {
 	Content             string   `json:"content,omitempty"`
 	Video               *Video   `json:"attached_video,omitempty"`
 	Images              []*Image `json:"attached_images,omitempty"`
+	LikeCount           int      `json:"num_likes,omitempty"`
+	CommentCount        int      `json:"num_comments,omitempty"`
+	// Comments are fetched separately when the album is downloaded, they aren't part of the API's album
+	Comments []*Comment `json:"-"`
+	// Enrollment is the child's class at the time the album was posted, set when listing
+	Enrollment *Enrollment `json:"-"`
+}
+
+type CommentPage struct {
+	Count    int        `json:"count,omitempty"`
+	Next     string     `json:"next,omitempty"`
+	Previous string     `json:"previous,omitempty"`
+	Results  []*Comment `json:"results,omitempty"`
+}
+
+// Comment is a comment on an album, AuthorRole tells teachers and parents apart
+type Comment struct {
+	ID         int        `json:"id,omitempty"`
+	AuthorName string     `json:"author_name,omitempty"`
+	AuthorRole string     `json:"author_type,omitempty"`
+	Created    string     `json:"created,omitempty"`
+	Content    string     `json:"content,omitempty"`
+	Replies    []*Comment `json:"replies,omitempty"`
 }
 
 type Video struct {
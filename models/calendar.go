@@ -0,0 +1,21 @@
+package models
+
+// Menu is a center's meal menu of a day
+type Menu struct {
+	ID             int    `json:"id,omitempty"`
+	Date           string `json:"date_menu,omitempty"`
+	MorningSnack   string `json:"morning_snack,omitempty"`
+	Lunch          string `json:"lunch,omitempty"`
+	AfternoonSnack string `json:"afternoon_snack,omitempty"`
+	Dinner         string `json:"dinner,omitempty"`
+}
+
+// ScheduleEvent is an entry of a center's or class' schedule, such as a field trip or a holiday.
+// The dates are days, the end date is inclusive.
+type ScheduleEvent struct {
+	ID        int    `json:"id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Content   string `json:"content,omitempty"`
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+}
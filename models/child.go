@@ -1,10 +1,58 @@
 package models
 
+import "time"
+
+// enrollmentDateLayout is the format of enrollment start and end dates
+const enrollmentDateLayout = "2006-01-02"
+
 type Child struct {
-	ID          int    `json:"id,omitempty"`
+	ID int `json:"id,omitempty"`
+	// CenterID, ClassID, CenterName and ClassName describe the current enrollment, they are empty
+	// for children who graduated
 	CenterID    int    `json:"center_id,omitempty"`
 	ClassID     int    `json:"class_id,omitempty"`
+	CenterName  string `json:"center_name,omitempty"`
+	ClassName   string `json:"class_name,omitempty"`
 	Name        string `json:"name,omitempty"`
 	Gender      string `json:"gender,omitempty"`
 	DateOfBirth string `json:"date_of_birth,omitempty"`
+	// Enrollments is the child's enrollment history, including past classes and centers
+	Enrollments []*Enrollment `json:"enrollments,omitempty"`
+}
+
+// Enrollment places a child in a class of a center from the Start date until the End date, both inclusive.
+// An empty Start means the beginning is unknown, an empty End that the child is still enrolled.
+type Enrollment struct {
+	CenterID   int    `json:"center_id,omitempty"`
+	CenterName string `json:"center_name,omitempty"`
+	ClassID    int    `json:"class_id,omitempty"`
+	ClassName  string `json:"class_name,omitempty"`
+	Start      string `json:"start,omitempty"`
+	End        string `json:"end,omitempty"`
+}
+
+// includes reports whether the enrollment covers the given time
+func (e *Enrollment) includes(t time.Time) bool {
+	if start, err := time.ParseInLocation(enrollmentDateLayout, e.Start, t.Location()); err == nil && t.Before(start) {
+		return false
+	}
+	if end, err := time.ParseInLocation(enrollmentDateLayout, e.End, t.Location()); err == nil && !t.Before(end.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// EnrollmentAt returns the enrollment covering the given time, the most recently started one if several do,
+// or nil if the child wasn't enrolled then
+func (c *Child) EnrollmentAt(t time.Time) *Enrollment {
+	var found *Enrollment
+	for _, enrollment := range c.Enrollments {
+		if !enrollment.includes(t) {
+			continue
+		}
+		if found == nil || enrollment.Start > found.Start {
+			found = enrollment
+		}
+	}
+	return found
 }
@@ -0,0 +1,37 @@
+package models
+
+type NoticePage struct {
+	Count    int       `json:"count,omitempty"`
+	Next     string    `json:"next,omitempty"`
+	Previous string    `json:"previous,omitempty"`
+	Results  []*Notice `json:"results,omitempty"`
+}
+
+// Notice is an announcement posted to a center or one of its classes, e.g. about a field trip or a policy change
+type Notice struct {
+	ID                  int           `json:"id,omitempty"`
+	GeneratedFolderName string        `json:"-"`
+	Created             string        `json:"created,omitempty"`
+	Title               string        `json:"title,omitempty"`
+	Content             string        `json:"content,omitempty"`
+	AuthorName          string        `json:"author_name,omitempty"`
+	Video               *Video        `json:"attached_video,omitempty"`
+	Images              []*Image      `json:"attached_images,omitempty"`
+	Files               []*Attachment `json:"attached_files,omitempty"`
+}
+
+// Attachment is a file attached to a notice, such as a PDF
+type Attachment struct {
+	ID           int    `json:"id,omitempty"`
+	FileName     string `json:"original_file_name,omitempty"`
+	FileSize     int    `json:"file_size,omitempty"`
+	DownloadLink string `json:"original,omitempty"`
+}
+
+// NoticeBoard is where notices are posted: a center, or a class of it if ClassID is set
+type NoticeBoard struct {
+	CenterID   int
+	CenterName string
+	ClassID    int
+	ClassName  string
+}
@@ -0,0 +1,39 @@
+package models
+
+type ReportPage struct {
+	Count    int       `json:"count,omitempty"`
+	Next     string    `json:"next,omitempty"`
+	Previous string    `json:"previous,omitempty"`
+	Results  []*Report `json:"results,omitempty"`
+}
+
+// Report is a daily report (알림장) about a child, written by a teacher and replied to by the parents
+type Report struct {
+	ID                  int    `json:"id,omitempty"`
+	GeneratedFolderName string `json:"-"`
+	// Date is the day the report is about, Created is when it was posted
+	Date       string `json:"date_written,omitempty"`
+	Created    string `json:"created,omitempty"`
+	AuthorName string `json:"author_name,omitempty"`
+	ClassName  string `json:"class_name,omitempty"`
+	Content    string `json:"content,omitempty"`
+
+	Weather     string `json:"weather,omitempty"`
+	Mood        string `json:"mood,omitempty"`
+	Health      string `json:"health,omitempty"`
+	Temperature string `json:"temperature,omitempty"`
+	Meal        string `json:"meal_status,omitempty"`
+	Sleep       string `json:"sleep_time,omitempty"`
+	Defecation  string `json:"defecation_status,omitempty"`
+
+	Video   *Video         `json:"attached_video,omitempty"`
+	Images  []*Image       `json:"attached_images,omitempty"`
+	Replies []*ReportReply `json:"comments,omitempty"`
+}
+
+type ReportReply struct {
+	ID         int    `json:"id,omitempty"`
+	AuthorName string `json:"author_name,omitempty"`
+	Created    string `json:"created,omitempty"`
+	Content    string `json:"content,omitempty"`
+}
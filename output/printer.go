@@ -0,0 +1,181 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats lists the supported --output values
+var Formats = []string{"table", "json", "jsonl", "csv", "yaml"}
+
+// Column is a named value of a row
+type Column struct {
+	Name  string
+	Value string
+	// Wide columns, such as long descriptions, are left out of tables and only written to CSV
+	Wide bool
+}
+
+// Row is an item to print. Tables and CSV use its columns, JSON and YAML marshal the row itself
+// and templates are executed with it.
+type Row interface {
+	Columns() []Column
+}
+
+// Printer writes rows in one of the supported formats. Flush must be called after the last row.
+type Printer struct {
+	w      io.Writer
+	format string
+	tmpl   *template.Template
+
+	table  *tabwriter.Writer
+	csv    *csv.Writer
+	header bool
+	rows   []Row
+}
+
+// NewPrinter creates a printer for the given format. A non-empty Go template, as in docker ps --format,
+// takes precedence over the format.
+func NewPrinter(w io.Writer, format, tmpl string) (*Printer, error) {
+	p := &Printer{w: w, format: format}
+
+	if tmpl != "" {
+		t, err := template.New("format").Funcs(template.FuncMap{"json": toJSON}).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format template: %w", err)
+		}
+		p.tmpl = t
+		return p, nil
+	}
+
+	switch format {
+	case "table":
+		p.table = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	case "csv":
+		p.csv = csv.NewWriter(w)
+	case "json", "jsonl", "yaml":
+	default:
+		return nil, fmt.Errorf("unsupported output format %q, use one of %s", format, strings.Join(Formats, ", "))
+	}
+
+	return p, nil
+}
+
+// Print writes the row, JSON and YAML output is written as a whole on Flush
+func (p *Printer) Print(row Row) error {
+	switch {
+	case p.tmpl != nil:
+		if err := p.tmpl.Execute(p.w, row); err != nil {
+			return fmt.Errorf("failed to execute format template: %w", err)
+		}
+		_, err := fmt.Fprintln(p.w)
+		return err
+	case p.table != nil:
+		return p.printTable(row)
+	case p.csv != nil:
+		return p.printCSV(row)
+	case p.format == "jsonl":
+		return json.NewEncoder(p.w).Encode(row)
+	default:
+		p.rows = append(p.rows, row)
+		return nil
+	}
+}
+
+// Flush writes out buffered output
+func (p *Printer) Flush() error {
+	switch {
+	case p.tmpl != nil:
+		return nil
+	case p.table != nil:
+		return p.table.Flush()
+	case p.csv != nil:
+		p.csv.Flush()
+		return p.csv.Error()
+	case p.format == "json":
+		rows := p.rows
+		if rows == nil {
+			rows = []Row{}
+		}
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case p.format == "yaml":
+		return p.flushYAML()
+	}
+	return nil
+}
+
+func (p *Printer) printTable(row Row) error {
+	var names, values []string
+	for _, column := range row.Columns() {
+		if column.Wide {
+			continue
+		}
+		names = append(names, strings.ToUpper(column.Name))
+		values = append(values, column.Value)
+	}
+
+	if !p.header {
+		p.header = true
+		if _, err := fmt.Fprintln(p.table, strings.Join(names, "\t")); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintln(p.table, strings.Join(values, "\t"))
+	return err
+}
+
+func (p *Printer) printCSV(row Row) error {
+	columns := row.Columns()
+
+	if !p.header {
+		p.header = true
+		names := make([]string, len(columns))
+		for i, column := range columns {
+			names[i] = column.Name
+		}
+		if err := p.csv.Write(names); err != nil {
+			return err
+		}
+	}
+
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = column.Value
+	}
+	return p.csv.Write(values)
+}
+
+// flushYAML writes the rows with the same field names as JSON output by going through their JSON form
+func (p *Printer) flushYAML() error {
+	content, err := json.Marshal(p.rows)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rows: %w", err)
+	}
+
+	var rows []any
+	if err := json.Unmarshal(content, &rows); err != nil {
+		return fmt.Errorf("failed to convert rows: %w", err)
+	}
+	if rows == nil {
+		rows = []any{}
+	}
+
+	encoder := yaml.NewEncoder(p.w)
+	defer encoder.Close()
+	return encoder.Encode(rows)
+}
+
+func toJSON(v any) (string, error) {
+	content, err := json.Marshal(v)
+	return string(content), err
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+var (
+	throttled = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rate_limit_throttled",
+		Help: "Whether requests are paused because the API asked to slow down (1) or not (0)",
+	}, []string{"budget"})
+	throttlePauses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limit_pauses_total",
+		Help: "The total number of pauses requested by the API through Retry-After",
+	}, []string{"budget"})
+	waitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rate_limit_wait_seconds",
+		Help:    "The time requests spent waiting for the rate limiter",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+	}, []string{"budget"})
+)
+
+// Limiter is a token bucket shared by all requests drawing on the same budget, e.g. API calls or media downloads.
+// It can additionally be paused when the API responds with Retry-After.
+type Limiter struct {
+	name    string
+	limiter *rate.Limiter
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// New creates a limiter allowing perSecond requests on average with bursts of up to burst requests.
+// A perSecond of 0 or less doesn't limit the rate, only Retry-After pauses apply.
+func New(name string, perSecond float64, burst int) *Limiter {
+	limit := rate.Limit(perSecond)
+	if perSecond <= 0 {
+		limit = rate.Inf
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	throttled.WithLabelValues(name).Set(0)
+
+	return &Limiter{
+		name:    name,
+		limiter: rate.NewLimiter(limit, burst),
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	start := time.Now()
+
+	if err := l.waitPause(ctx); err != nil {
+		return err
+	}
+	if err := l.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	waitDuration.WithLabelValues(l.name).Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// Pause holds back all requests for d, unless they are already held back for longer
+func (l *Limiter) Pause(d time.Duration) {
+	until := time.Now().Add(d)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !until.After(l.pausedUntil) {
+		return
+	}
+	l.pausedUntil = until
+
+	throttled.WithLabelValues(l.name).Set(1)
+	throttlePauses.WithLabelValues(l.name).Inc()
+	time.AfterFunc(d, l.resume)
+}
+
+// waitPause blocks while the limiter is paused, the pause may get extended while waiting
+func (l *Limiter) waitPause(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		remaining := time.Until(l.pausedUntil)
+		l.mu.Unlock()
+
+		if remaining <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *Limiter) resume() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !time.Now().Before(l.pausedUntil) {
+		throttled.WithLabelValues(l.name).Set(0)
+	}
+}
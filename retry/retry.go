@@ -0,0 +1,188 @@
+package retry
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+	"github.com/karolistamutis/kidsnoter/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	retriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "retries_total",
+		Help: "The total number of retried operations by the reason of the failed attempt",
+	}, []string{"operation", "reason"})
+	failuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "retry_failures_total",
+		Help: "The total number of operations that failed for good, either on a permanent error or out of attempts",
+	}, []string{"operation", "reason"})
+)
+
+// Policy controls how often and how long an operation is retried
+type Policy struct {
+	// Operation names the kind of operation in logs and metrics, e.g. listing or image
+	Operation   string
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Policies holds the retry policy of every kind of operation
+type Policies struct {
+	Login   Policy
+	Listing Policy
+	Image   Policy
+	Video   Policy
+}
+
+// Do runs operation until it succeeds, fails with a permanent error or runs out of attempts, waiting with
+// exponential backoff and jitter in between. A delay requested by the server takes precedence when it is longer.
+func (p Policy) Do(ctx context.Context, operation func() error) error {
+	maxAttempts := max(p.MaxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil {
+			return nil
+		}
+
+		// The caller gave up, no point in classifying errors caused by that
+		if ctx.Err() != nil {
+			return err
+		}
+
+		reason, transient := Classify(err)
+		if !transient || attempt == maxAttempts {
+			failuresTotal.WithLabelValues(p.Operation, reason).Inc()
+			return err
+		}
+
+		delay := p.backoff(attempt, err)
+		retriesTotal.WithLabelValues(p.Operation, reason).Inc()
+		logger.Log.Infow("Retrying after failed attempt",
+			"operation", p.Operation,
+			"attempt", fmt.Sprintf("%d/%d", attempt, maxAttempts),
+			"reason", reason,
+			"delay", delay.Round(time.Millisecond),
+			"error", err,
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			// Continue to next attempt
+		}
+	}
+}
+
+// backoff returns the delay after the given failed attempt
+func (p Policy) backoff(attempt int, err error) time.Duration {
+	backoff := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.MaxDelay > 0 && backoff > float64(p.MaxDelay) {
+		backoff = float64(p.MaxDelay)
+	}
+
+	jitter := rand.Float64() * backoff * 0.1
+	delay := time.Duration(backoff + jitter)
+
+	var apiErr *kidsnote.APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter() > delay {
+		delay = apiErr.RetryAfter()
+	}
+
+	return delay
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Classify returns a short reason for the error and whether it is transient, i.e. likely to go away on a retry.
+// Errors not known to be transient are permanent.
+func Classify(err error) (string, bool) {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return "permanent", false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return "canceled", false
+	}
+
+	var apiErr *kidsnote.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return "rate_limited", true
+		case apiErr.StatusCode == http.StatusRequestTimeout:
+			return "timeout", true
+		case apiErr.StatusCode >= http.StatusInternalServerError:
+			return "server_error", true
+		case errors.Is(apiErr, kidsnote.ErrUnauthorized):
+			return "unauthorized", false
+		case errors.Is(apiErr, kidsnote.ErrNotFound):
+			return "not_found", false
+		}
+		return "client_error", false
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var schemaErr *kidsnote.SchemaError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.As(err, &schemaErr) {
+		return "invalid_response", false
+	}
+
+	var certErr *x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &certErr) || errors.As(err, &hostnameErr) {
+		return "tls", false
+	}
+
+	// A per-request timeout, the operation's own context is checked separately
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout", true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout", true
+	}
+
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return "network", true
+	}
+
+	return "other", false
+}
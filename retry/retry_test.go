@@ -0,0 +1,136 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/kidsnote"
+)
+
+func TestClassify(t *testing.T) {
+	var syntaxErr error = &json.SyntaxError{}
+
+	tests := []struct {
+		name          string
+		err           error
+		wantReason    string
+		wantTransient bool
+	}{
+		{"rate limited", &kidsnote.APIError{StatusCode: 429}, "rate_limited", true},
+		{"request timeout", &kidsnote.APIError{StatusCode: 408}, "timeout", true},
+		{"server error", &kidsnote.APIError{StatusCode: 503}, "server_error", true},
+		{"unauthorized", &kidsnote.APIError{StatusCode: 401}, "unauthorized", false},
+		{"forbidden", &kidsnote.APIError{StatusCode: 403}, "unauthorized", false},
+		{"not found", &kidsnote.APIError{StatusCode: 404}, "not_found", false},
+		{"gone", &kidsnote.APIError{StatusCode: 410}, "not_found", false},
+		{"bad request", &kidsnote.APIError{StatusCode: 400}, "client_error", false},
+		{"wrapped server error", fmt.Errorf("listing: %w", &kidsnote.APIError{StatusCode: 502}), "server_error", true},
+		{"marked permanent", Permanent(&kidsnote.APIError{StatusCode: 503}), "permanent", false},
+		{"canceled", context.Canceled, "canceled", false},
+		{"request deadline", fmt.Errorf("get: %w", context.DeadlineExceeded), "timeout", true},
+		{"malformed JSON", syntaxErr, "invalid_response", false},
+		{"schema mismatch", &kidsnote.SchemaError{}, "invalid_response", false},
+		{"connection reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, "network", true},
+		{"dropped connection", io.ErrUnexpectedEOF, "network", true},
+		{"unknown", errors.New("something else"), "other", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, transient := Classify(tt.err)
+			if reason != tt.wantReason || transient != tt.wantTransient {
+				t.Errorf("Classify(%v) = %s, %v, want %s, %v", tt.err, reason, transient, tt.wantReason, tt.wantTransient)
+			}
+		})
+	}
+}
+
+func TestPolicyDo(t *testing.T) {
+	transient := &kidsnote.APIError{StatusCode: 503}
+	permanent := &kidsnote.APIError{StatusCode: 404}
+
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{"success", 3, nil, 1, nil},
+		{"transient then success", 3, []error{transient, transient}, 3, nil},
+		{"out of attempts", 3, []error{transient, transient, transient, transient}, 3, transient},
+		{"permanent stops right away", 3, []error{permanent}, 1, permanent},
+		{"at least one attempt", 0, []error{transient}, 1, transient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := Policy{Operation: "test", MaxAttempts: tt.maxAttempts, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+			attempts := 0
+			err := policy.Do(context.Background(), func() error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("made %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyDoStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := Policy{Operation: "test", MaxAttempts: 5, BaseDelay: time.Hour}
+
+	attempts := 0
+	done := make(chan error)
+	go func() {
+		done <- policy.Do(ctx, func() error {
+			attempts++
+			return &kidsnote.APIError{StatusCode: 503}
+		})
+	}()
+
+	// The first attempt failed and the policy waits an hour before the next one
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Do kept waiting after the context was canceled")
+	}
+	if attempts != 1 {
+		t.Errorf("made %d attempts, want 1", attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 6: time.Second} {
+		delay := policy.backoff(attempt, errors.New("failed"))
+		// Up to 10% of jitter is added
+		if delay < want || delay > want+want/10 {
+			t.Errorf("backoff after attempt %d is %v, want %v plus up to 10%%", attempt, delay, want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/util"
+)
+
+// ErrNotFound is returned when no session has been stored for a user
+var ErrNotFound = errors.New("no stored session")
+
+// Session holds the cookie values needed to reuse an authenticated Kidsnote session
+type Session struct {
+	Username  string    `json:"current_user"`
+	SessionID string    `json:"session_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store persists sessions on disk, one file per username
+type Store struct {
+	dir string
+}
+
+// NewStore creates a session store rooted at the given directory
+func NewStore(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("session directory is not set")
+	}
+
+	dir, err := util.ExpandTilde(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand session directory path: %w", err)
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+// Path returns the location of the session file for the given username
+func (s *Store) Path(username string) string {
+	return filepath.Join(s.dir, url.PathEscape(username)+".json")
+}
+
+// Load reads the stored session for the given username
+func (s *Store) Load(username string) (*Session, error) {
+	content, err := os.ReadFile(s.Path(username))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	var sess Session
+	if err := json.Unmarshal(content, &sess); err != nil {
+		return nil, fmt.Errorf("failed to parse session file: %w", err)
+	}
+
+	if sess.SessionID == "" || sess.Username != username {
+		return nil, ErrNotFound
+	}
+
+	return &sess, nil
+}
+
+// Save writes the session to disk, readable only by the current user
+func (s *Store) Save(sess *Session) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	content, err := json.Marshal(sess)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	path := s.Path(sess.Username)
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	// WriteFile keeps the mode of an already existing file, make sure it's restricted
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to set session file permissions: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the stored session for the given username, if any
+func (s *Store) Delete(username string) error {
+	if err := os.Remove(s.Path(username)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session file: %w", err)
+	}
+	return nil
+}
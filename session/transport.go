@@ -0,0 +1,157 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// reloginCooldown prevents hammering the login endpoint when logging in again keeps failing
+	reloginCooldown = time.Minute
+	// reloginInterval is the least time between two logins, as frequent password logins get flagged as suspicious.
+	// A session rejected again this soon after logging in isn't expired, so logging in wouldn't help anyway.
+	reloginInterval = 5 * time.Minute
+)
+
+type skipReauthKey struct{}
+
+// WithoutReauth marks requests made with the returned context as exempt from re-authentication,
+// which is needed for the login request itself
+func WithoutReauth(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipReauthKey{}, true)
+}
+
+// LoginFunc performs a fresh login and stores the new session cookies in the cookie jar
+type LoginFunc func(ctx context.Context) error
+
+// Transport is an http.RoundTripper that logs in again once the API reports the session has expired
+// and replays the failed request with the new session cookies
+type Transport struct {
+	base  http.RoundTripper
+	jar   http.CookieJar
+	login LoginFunc
+	// apiHosts are the hosts whose auth failures mean an expired session
+	apiHosts map[string]bool
+
+	mu          sync.Mutex
+	generation  uint64
+	lastErr     error
+	lastErrTime time.Time
+	lastLogin   time.Time
+}
+
+// NewTransport wraps base (http.DefaultTransport if nil) with transparent re-authentication of requests to
+// the given API hosts, at least one is required. Requests to other hosts, such as photos and videos, are passed
+// through: their 403s come from e.g. expired links, which a new session doesn't fix.
+func NewTransport(base http.RoundTripper, jar http.CookieJar, login LoginFunc, apiHosts []string) (*Transport, error) {
+	if len(apiHosts) == 0 {
+		return nil, fmt.Errorf("no API hosts to re-authenticate requests to")
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	hosts := make(map[string]bool, len(apiHosts))
+	for _, host := range apiHosts {
+		hosts[strings.ToLower(host)] = true
+	}
+
+	return &Transport{
+		base:     base,
+		jar:      jar,
+		login:    login,
+		apiHosts: hosts,
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if skip, _ := req.Context().Value(skipReauthKey{}).(bool); skip || !t.apiHosts[strings.ToLower(req.URL.Hostname())] {
+		return t.base.RoundTrip(req)
+	}
+
+	// A request whose body can't be rewound can't be replayed either
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	t.mu.Lock()
+	generation := t.generation
+	t.mu.Unlock()
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || !isAuthFailure(resp) {
+		return resp, err
+	}
+
+	// The response is thrown away in favour of the replayed one
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if err := t.relogin(req.Context(), generation); err != nil {
+		return nil, fmt.Errorf("session expired and re-authentication failed: %w", err)
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		retry.Body = body
+	}
+
+	retry.Header.Del("Cookie")
+	for _, cookie := range t.jar.Cookies(req.URL) {
+		retry.AddCookie(cookie)
+	}
+
+	return t.base.RoundTrip(retry)
+}
+
+// relogin logs in again unless another request already did so since generation was observed
+func (t *Transport) relogin(ctx context.Context, generation uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.generation != generation {
+		return nil
+	}
+
+	if t.lastErr != nil && time.Since(t.lastErrTime) < reloginCooldown {
+		return t.lastErr
+	}
+
+	if since := time.Since(t.lastLogin); since < reloginInterval {
+		return fmt.Errorf("session rejected %s after logging in, not logging in again before %s have passed",
+			since.Round(time.Second), reloginInterval)
+	}
+
+	if err := t.login(WithoutReauth(ctx)); err != nil {
+		t.lastErr = err
+		t.lastErrTime = time.Now()
+		return err
+	}
+
+	t.lastErr = nil
+	t.lastLogin = time.Now()
+	t.generation++
+	return nil
+}
+
+// isAuthFailure reports whether the response indicates a missing or expired session
+func isAuthFailure(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
+		location, err := resp.Location()
+		return err == nil && strings.Contains(location.Path, "login")
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	apiURL   = "https://api.example.com/v1/albums"
+	mediaURL = "https://media.example.com/photo.jpg"
+)
+
+// fakeKidsnote answers API requests with 401 unless they carry the current session cookie, and media requests with
+// the given status. Requests are served by an httptest.ResponseRecorder, as the transport routes by host name.
+type fakeKidsnote struct {
+	mediaStatus int
+	// hold, if set, keeps API requests with an outdated session waiting until it is closed
+	hold chan struct{}
+
+	mu      sync.Mutex
+	session string
+	logins  atomic.Int32
+	calls   atomic.Int32
+}
+
+func (f *fakeKidsnote) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.calls.Add(1)
+	rec := httptest.NewRecorder()
+
+	if req.URL.Host != "api.example.com" {
+		rec.WriteHeader(f.mediaStatus)
+		return rec.Result(), nil
+	}
+
+	cookie, err := req.Cookie("session")
+	f.mu.Lock()
+	valid := err == nil && cookie.Value == f.session
+	f.mu.Unlock()
+
+	if !valid {
+		if f.hold != nil {
+			<-f.hold
+		}
+		http.Error(rec, "unauthorized", http.StatusUnauthorized)
+		return rec.Result(), nil
+	}
+	io.WriteString(rec, "ok")
+	return rec.Result(), nil
+}
+
+// login starts a new session and stores its cookie in the jar
+func (f *fakeKidsnote) login(jar http.CookieJar) LoginFunc {
+	return func(ctx context.Context) error {
+		if skip, _ := ctx.Value(skipReauthKey{}).(bool); !skip {
+			return errors.New("login request isn't exempt from re-authentication")
+		}
+
+		n := f.logins.Add(1)
+		f.mu.Lock()
+		f.session = fmt.Sprintf("session-%d", n)
+		session := f.session
+		f.mu.Unlock()
+
+		u, _ := url.Parse(apiURL)
+		jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: session}})
+		return nil
+	}
+}
+
+func newTestClient(t *testing.T, api *fakeKidsnote) *http.Client {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookiejar.New: %v", err)
+	}
+	u, _ := url.Parse(apiURL)
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "expired"}})
+
+	transport, err := NewTransport(api, jar, api.login(jar), []string{"API.example.com"})
+	if err != nil {
+		t.Fatalf("NewTransport: %v", err)
+	}
+	return &http.Client{Transport: transport, Jar: jar}
+}
+
+func get(t *testing.T, ctx context.Context, client *http.Client, rawURL string) (int, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestTransportRelogsOnExpiredSession(t *testing.T) {
+	api := &fakeKidsnote{session: "current"}
+	client := newTestClient(t, api)
+
+	status, err := get(t, context.Background(), client, apiURL)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("got status %d, error %v, want the replayed request to succeed", status, err)
+	}
+	if n := api.logins.Load(); n != 1 {
+		t.Errorf("logged in %d times, want 1", n)
+	}
+
+	// The new session is used from now on
+	if status, err := get(t, context.Background(), client, apiURL); err != nil || status != http.StatusOK {
+		t.Errorf("got status %d, error %v, want 200", status, err)
+	}
+	if n := api.logins.Load(); n != 1 {
+		t.Errorf("logged in %d times, want 1", n)
+	}
+}
+
+func TestTransportPassesThroughMedia(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		api := &fakeKidsnote{mediaStatus: status}
+		client := newTestClient(t, api)
+
+		got, err := get(t, context.Background(), client, mediaURL)
+		if err != nil || got != status {
+			t.Errorf("got status %d, error %v, want %d passed through", got, err, status)
+		}
+		if n := api.logins.Load(); n != 0 {
+			t.Errorf("media status %d logged in %d times, want none", status, n)
+		}
+	}
+}
+
+func TestTransportWithoutReauth(t *testing.T) {
+	api := &fakeKidsnote{session: "current"}
+	client := newTestClient(t, api)
+
+	status, err := get(t, WithoutReauth(context.Background()), client, apiURL)
+	if err != nil || status != http.StatusUnauthorized {
+		t.Errorf("got status %d, error %v, want 401 passed through", status, err)
+	}
+	if n := api.logins.Load(); n != 0 {
+		t.Errorf("logged in %d times, want none", n)
+	}
+}
+
+func TestTransportSharesOneLogin(t *testing.T) {
+	api := &fakeKidsnote{session: "current", hold: make(chan struct{})}
+	client := newTestClient(t, api)
+
+	const requests = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status, err := get(t, context.Background(), client, apiURL); err != nil || status != http.StatusOK {
+				errs <- fmt.Errorf("status %d, error %v", status, err)
+			}
+		}()
+	}
+
+	// Let the requests fail together once all of them were sent with the expired session
+	for api.calls.Load() < requests {
+		time.Sleep(time.Millisecond)
+	}
+	close(api.hold)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("request failed: %v", err)
+	}
+	if n := api.logins.Load(); n != 1 {
+		t.Errorf("logged in %d times for %d expired requests, want 1", n, requests)
+	}
+}
+
+func TestTransportLimitsRelogins(t *testing.T) {
+	api := &fakeKidsnote{session: "current"}
+	client := newTestClient(t, api)
+
+	if status, err := get(t, context.Background(), client, apiURL); err != nil || status != http.StatusOK {
+		t.Fatalf("got status %d, error %v, want 200", status, err)
+	}
+
+	// The session is rejected again right after logging in, e.g. because the account is locked
+	api.mu.Lock()
+	api.session = "elsewhere"
+	api.mu.Unlock()
+
+	_, err := get(t, context.Background(), client, apiURL)
+	if err == nil || !strings.Contains(err.Error(), "not logging in again") {
+		t.Errorf("got error %v, want re-authentication refused", err)
+	}
+	if n := api.logins.Load(); n != 1 {
+		t.Errorf("logged in %d times, want 1", n)
+	}
+}
+
+func TestTransportLoginFailureCooldown(t *testing.T) {
+	jar, _ := cookiejar.New(nil)
+	api := &fakeKidsnote{session: "current"}
+	var logins atomic.Int32
+	transport, err := NewTransport(api, jar, func(ctx context.Context) error {
+		logins.Add(1)
+		return errors.New("wrong password")
+	}, []string{"api.example.com"})
+	if err != nil {
+		t.Fatalf("NewTransport: %v", err)
+	}
+	client := &http.Client{Transport: transport, Jar: jar}
+
+	for range 3 {
+		_, err := get(t, context.Background(), client, apiURL)
+		if err == nil || !strings.Contains(err.Error(), "wrong password") {
+			t.Errorf("got error %v, want the login failure", err)
+		}
+	}
+	if n := logins.Load(); n != 1 {
+		t.Errorf("tried logging in %d times within the cooldown, want 1", n)
+	}
+}
+
+func TestNewTransportRequiresHosts(t *testing.T) {
+	jar, _ := cookiejar.New(nil)
+	if _, err := NewTransport(nil, jar, func(ctx context.Context) error { return nil }, nil); err == nil {
+		t.Error("NewTransport accepted no API hosts, which would turn re-authentication off")
+	}
+}
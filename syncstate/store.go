@@ -0,0 +1,99 @@
+package syncstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/karolistamutis/kidsnoter/util"
+)
+
+// ErrNotFound is returned when none of the child's albums or reports have been synced yet
+var ErrNotFound = errors.New("no sync state")
+
+// dirName is the directory inside the album directory holding the sync state
+const dirName = ".kidsnoter"
+
+// Kinds of synced items, each with its own marks
+const (
+	Albums  = "albums"
+	Reports = "reports"
+)
+
+// Mark is the high-water mark of a child's albums or reports: the newest one as of the last sync that finished without errors
+type Mark struct {
+	ChildID int `json:"child_id"`
+	// ID is the album or report ID
+	ID       int       `json:"id"`
+	Created  time.Time `json:"created"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// Store persists marks next to the albums they describe, so a restored or emptied album directory starts over
+type Store struct {
+	dir  string
+	kind string
+}
+
+// NewStore creates a store for the albums or reports, as given by kind, saved under albumDir
+func NewStore(albumDir, kind string) (*Store, error) {
+	if albumDir == "" {
+		return nil, fmt.Errorf("album directory is not set")
+	}
+
+	albumDir, err := util.ExpandTilde(albumDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand album directory path: %w", err)
+	}
+
+	return &Store{dir: filepath.Join(albumDir, dirName), kind: kind}, nil
+}
+
+// Path returns the location of the mark file for the given child
+func (s *Store) Path(childID int) string {
+	return filepath.Join(s.dir, s.kind+"-"+strconv.Itoa(childID)+".json")
+}
+
+// Load reads the mark of the given child
+func (s *Store) Load(childID int) (*Mark, error) {
+	content, err := os.ReadFile(s.Path(childID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read sync state file: %w", err)
+	}
+
+	var mark Mark
+	if err := json.Unmarshal(content, &mark); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state file: %w", err)
+	}
+
+	if mark.ChildID != childID || mark.Created.IsZero() {
+		return nil, ErrNotFound
+	}
+
+	return &mark, nil
+}
+
+// Save writes the mark to disk
+func (s *Store) Save(mark *Mark) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create sync state directory: %w", err)
+	}
+
+	content, err := json.Marshal(mark)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync state: %w", err)
+	}
+
+	if err := util.WriteFileAtomic(s.Path(mark.ChildID), content); err != nil {
+		return fmt.Errorf("failed to write sync state file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package syncstate
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStoreSaveLoad(t *testing.T) {
+	store, err := NewStore(t.TempDir(), Albums)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+
+	if _, err := store.Load(1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got error %v before the first sync, want ErrNotFound", err)
+	}
+
+	want := &Mark{ChildID: 1, ID: 42, Created: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), SyncedAt: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC)}
+	if err := store.Save(want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := store.Load(1)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.ChildID != want.ChildID || got.ID != want.ID || !got.Created.Equal(want.Created) || !got.SyncedAt.Equal(want.SyncedAt) {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+
+	if _, err := store.Load(2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for another child, want ErrNotFound", err)
+	}
+}
+
+func TestStoreKindsAreSeparate(t *testing.T) {
+	dir := t.TempDir()
+	albums, _ := NewStore(dir, Albums)
+	reports, _ := NewStore(dir, Reports)
+
+	if err := albums.Save(&Mark{ChildID: 1, ID: 42, Created: time.Now()}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := reports.Load(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for reports after syncing albums, want ErrNotFound", err)
+	}
+}
+
+func TestStoreLoadIgnoresUnusableMarks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{"other child", `{"child_id": 2, "id": 42, "created": "2024-05-01T12:00:00Z"}`, ErrNotFound},
+		{"no created date", `{"child_id": 1, "id": 42}`, ErrNotFound},
+		{"corrupt", `{"child_id": 1,`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := NewStore(t.TempDir(), Albums)
+			if err := os.MkdirAll(store.dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(store.Path(1), []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			mark, err := store.Load(1)
+			if err == nil {
+				t.Fatalf("loaded %+v, want an error", mark)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && errors.Is(err, ErrNotFound) {
+				t.Errorf("corrupt mark reported as missing, which would silently start over")
+			}
+		})
+	}
+}
+
+func TestNewStoreRequiresAlbumDir(t *testing.T) {
+	if _, err := NewStore("", Albums); err == nil {
+		t.Error("NewStore accepted an empty album directory")
+	}
+}
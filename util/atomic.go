@@ -0,0 +1,131 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PartSuffix ends the names of files still being written, which are hidden and renamed into place once complete
+const PartSuffix = ".part"
+
+// AtomicFile is written to a hidden .part file next to its destination and renamed into place on Commit,
+// so photo indexers and later runs never see it half-written
+type AtomicFile struct {
+	*os.File
+	path string
+	done bool
+}
+
+// CreateAtomic starts writing the file at path, which is only created or replaced on Commit
+func CreateAtomic(path string) (*AtomicFile, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*"+PartSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return &AtomicFile{File: tmp, path: path}, nil
+}
+
+// Commit flushes the file to disk and renames it into place
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return fmt.Errorf("file %s already committed or aborted", f.path)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		f.done = true
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		f.done = true
+		os.Remove(f.Name())
+		return err
+	}
+	f.done = true
+
+	// Persist the rename too, some filesystems don't support syncing directories
+	if dir, err := os.Open(filepath.Dir(f.path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+
+	return nil
+}
+
+// Abort removes the unfinished file, leaving the destination as it was. It does nothing after Commit, so it can be deferred.
+func (f *AtomicFile) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.File.Close()
+	os.Remove(f.Name())
+}
+
+// WriteFileAtomic writes content to the file at path like os.WriteFile, but through a .part file
+func WriteFileAtomic(path string, content []byte) error {
+	f, err := CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if _, err := f.Write(content); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// RemoveStaleParts deletes the .part files under dir left by interrupted runs and returns how many there were.
+// Only files unmodified for at least minAge are removed, so those another process is still writing are left alone.
+func RemoveStaleParts(dir string, minAge time.Duration) (int, error) {
+	cutoff := time.Now().Add(-minAge)
+	removed := 0
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, ".") || !strings.HasSuffix(name, PartSuffix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
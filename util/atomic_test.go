@@ -0,0 +1,158 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parts returns the names of the .part files in dir
+func parts(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), PartSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func TestAtomicFileCommit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := CreateAtomic(path)
+	if err != nil {
+		t.Fatalf("CreateAtomic: %v", err)
+	}
+	defer f.Abort()
+
+	if _, err := f.WriteString("new"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// The destination is left alone until the file is complete
+	if content, _ := os.ReadFile(path); string(content) != "old" {
+		t.Errorf("destination holds %q before Commit, want the old content", content)
+	}
+	if names := parts(t, dir); len(names) != 1 || !strings.HasPrefix(names[0], ".photo.jpg.") {
+		t.Errorf("got part files %q, want one hidden part file named after the destination", names)
+	}
+
+	if err := f.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "new" {
+		t.Errorf("destination holds %q after Commit, want the new content", content)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("got mode %v, error %v, want 0644", info.Mode().Perm(), err)
+	}
+	if names := parts(t, dir); len(names) != 0 {
+		t.Errorf("part files %q left after Commit", names)
+	}
+	if err := f.Commit(); err == nil {
+		t.Error("committed the same file twice")
+	}
+}
+
+func TestAtomicFileAbort(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.jpg")
+
+	f, err := CreateAtomic(path)
+	if err != nil {
+		t.Fatalf("CreateAtomic: %v", err)
+	}
+	f.WriteString("partial")
+	f.Abort()
+	f.Abort()
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("got error %v, want the destination not created", err)
+	}
+	if names := parts(t, dir); len(names) != 0 {
+		t.Errorf("part files %q left after Abort", names)
+	}
+	if err := f.Commit(); err == nil {
+		t.Error("committed an aborted file")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "description.md")
+
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("WriteFileAtomic: %v", err)
+		}
+		if got, _ := os.ReadFile(path); string(got) != content {
+			t.Errorf("file holds %q, want %q", got, content)
+		}
+	}
+	if names := parts(t, dir); len(names) != 0 {
+		t.Errorf("part files %q left behind", names)
+	}
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "description.md"), nil); err == nil {
+		t.Error("wrote into a directory that doesn't exist")
+	}
+}
+
+func TestRemoveStaleParts(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+
+	files := []struct {
+		path     string
+		modified time.Time
+		wantGone bool
+	}{
+		{"2024/05/picnic/.photo.jpg.123" + PartSuffix, old, true},
+		{".description.md.456" + PartSuffix, old, true},
+		{"2024/05/picnic/.photo2.jpg.789" + PartSuffix, time.Now(), false},
+		{"2024/05/picnic/photo.jpg", old, false},
+		{"2024/05/picnic/visible" + PartSuffix, old, false},
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file.path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, file.modified, file.modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := RemoveStaleParts(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("RemoveStaleParts: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d files, want 2", removed)
+	}
+	for _, file := range files {
+		_, err := os.Stat(filepath.Join(dir, file.path))
+		if gone := os.IsNotExist(err); gone != file.wantGone {
+			t.Errorf("%s removed: %v, want %v", file.path, gone, file.wantGone)
+		}
+	}
+
+	if removed, err := RemoveStaleParts(filepath.Join(dir, "missing"), time.Hour); err != nil || removed != 0 {
+		t.Errorf("got %d, %v for a missing directory, want nothing removed and no error", removed, err)
+	}
+}
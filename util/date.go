@@ -0,0 +1,57 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateOnly = "2006-01-02"
+
+// ParseTimeBound parses a point in time given either as an absolute date (2006-01-02, in local time),
+// an RFC 3339 timestamp, or a duration before now such as 30d, 2w or 12h.
+// A date given for an upper bound stands for the end of that day.
+func ParseTimeBound(value string, now time.Time, upper bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation(dateOnly, value, time.Local); err == nil {
+		if upper {
+			return t.AddDate(0, 0, 1), nil
+		}
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	d, err := parseRelative(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use a date like 2024-05-31, an RFC 3339 timestamp or a duration like 30d", value)
+	}
+
+	return now.Add(-d), nil
+}
+
+// parseRelative parses a duration, additionally accepting days (d) and weeks (w)
+func parseRelative(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
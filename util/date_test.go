@@ -0,0 +1,51 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 5, 31, 15, 30, 0, 0, time.UTC)
+	may1 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		value   string
+		upper   bool
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "empty is unbounded", value: "  ", want: time.Time{}},
+		{name: "date as lower bound", value: "2024-05-01", want: may1},
+		{name: "date as upper bound includes the day", value: "2024-05-01", upper: true, want: may1.AddDate(0, 0, 1)},
+		{name: "timestamp", value: "2024-05-01T08:00:00+02:00", upper: true, want: time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)},
+		{name: "days", value: "30d", want: now.AddDate(0, 0, -30)},
+		{name: "weeks", value: "2w", want: now.AddDate(0, 0, -14)},
+		{name: "hours", value: "12h", want: now.Add(-12 * time.Hour)},
+		{name: "padded", value: " 1d ", want: now.AddDate(0, 0, -1)},
+		{name: "negative days", value: "-3d", wantErr: true},
+		{name: "negative duration", value: "-3h", wantErr: true},
+		{name: "unknown unit", value: "3y", wantErr: true},
+		{name: "invalid date", value: "2024-13-01", wantErr: true},
+		{name: "garbage", value: "last tuesday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimeBound(tt.value, now, tt.upper)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseTimeBound(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTimeBound(%q): %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTimeBound(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
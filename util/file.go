@@ -10,6 +10,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -64,6 +65,71 @@ func GenerateFolderName(childName string, date string, albumID int, albumTitle s
 	return fmt.Sprintf("%s/%s_%d_%s", normalizedChildName, formattedDate, albumID, normalizedTitle), nil
 }
 
+// GenerateReportFolderName returns the folder of a daily report within the child's folder, e.g. Child/reports/2024/05/31_123
+func GenerateReportFolderName(childName string, date string, reportID int) (string, error) {
+	normalizedChildName, err := normalize(childName)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize the child name for report directory creation (%s): %v", childName, err)
+	}
+
+	day, err := time.Parse(dateOnly, date)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse report date %q: %v", date, err)
+	}
+
+	// childName/reports/YYYY/MM/DD_reportID
+	return fmt.Sprintf("%s/reports/%s_%d", normalizedChildName, day.Format("2006/01/02"), reportID), nil
+}
+
+// GenerateNoticeBoardFolderName returns the folder of a center's notices, or of its class' notices if classID is set,
+// e.g. notices/Sunshine_12/Rabbits_34. The IDs keep apart centers or classes with the same name.
+func GenerateNoticeBoardFolderName(centerName string, centerID int, className string, classID int) (string, error) {
+	centerFolder, err := namedFolder(centerName, centerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize the center name for notice directory creation (%s): %v", centerName, err)
+	}
+
+	if classID == 0 {
+		return fmt.Sprintf("notices/%s", centerFolder), nil
+	}
+
+	classFolder, err := namedFolder(className, classID)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize the class name for notice directory creation (%s): %v", className, err)
+	}
+
+	return fmt.Sprintf("notices/%s/%s", centerFolder, classFolder), nil
+}
+
+// GenerateNoticeFolderName returns the folder of a notice within its board's folder
+func GenerateNoticeFolderName(boardFolder string, date string, noticeID int, title string) (string, error) {
+	formattedDate, err := formatDate(date)
+	if err != nil {
+		// Fallback to using unformatted date
+		formattedDate = date
+	}
+
+	normalizedTitle, err := normalize(title)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize the title for notice directory creation (%s): %v", title, err)
+	}
+
+	// boardFolder/YYYY/MM_noticeID_title
+	return fmt.Sprintf("%s/%s_%d_%s", boardFolder, formattedDate, noticeID, normalizedTitle), nil
+}
+
+// namedFolder returns name_id, or just the ID if the name is empty
+func namedFolder(name string, id int) (string, error) {
+	normalized, err := normalize(name)
+	if err != nil {
+		return "", err
+	}
+	if normalized == "" {
+		return strconv.Itoa(id), nil
+	}
+	return fmt.Sprintf("%s_%d", normalized, id), nil
+}
+
 func ExpandTilde(path string) (string, error) {
 	if strings.HasPrefix(path, "~/") {
 		homeDir, err := os.UserHomeDir()
@@ -83,8 +149,13 @@ func FileExistsAndMatches(filepath string, expectedSize int) bool {
 	return false
 }
 
+// ParseAlbumDate parses the created timestamp of an album as returned by the API
+func ParseAlbumDate(date string) (time.Time, error) {
+	return time.Parse(layout, date)
+}
+
 func formatDate(date string) (string, error) {
-	t, err := time.Parse(layout, date)
+	t, err := ParseAlbumDate(date)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse date: %v", err)
 	}